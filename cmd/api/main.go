@@ -1,6 +1,7 @@
 package main
 
 import (
+	"cmp"
 	"context"
 	"log"
 	"net/http"
@@ -11,13 +12,16 @@ import (
 
 	"github.com/redis/go-redis/v9"
 
+	"automessaging/internal/circuitbreaker"
 	"automessaging/internal/config"
 	dbpkg "automessaging/internal/db"
 	httpserver "automessaging/internal/http"
 	"automessaging/internal/http/handler"
+	"automessaging/internal/ratelimit"
 	"automessaging/internal/repository/postgres"
 	"automessaging/internal/scheduler"
 	"automessaging/internal/service"
+	"automessaging/pkg/webhooksig"
 )
 
 func main() {
@@ -29,8 +33,8 @@ func main() {
 		log.Fatalf("load config: %v", err)
 	}
 
-	if cfg.Webhook.URL == "" {
-		log.Fatal("WEBHOOK_URL environment variable must be set")
+	if len(cfg.Webhook.Destinations) == 0 {
+		log.Fatal("WEBHOOK_URL or WEBHOOK_DESTINATIONS environment variable must be set")
 	}
 
 	database, err := dbpkg.Connect(cfg.Postgres)
@@ -52,17 +56,114 @@ func main() {
 
 	repo := postgres.NewMessageRepository(database)
 
+	var signer *webhooksig.Signer
+	if len(cfg.Webhook.SigningSecrets) > 0 {
+		if signer, err = webhooksig.NewSigner(cfg.Webhook.SigningSecrets...); err != nil {
+			log.Fatalf("configure webhook signing: %v", err)
+		}
+	}
+
+	destinations := make([]service.Destination, 0, len(cfg.Webhook.Destinations))
+	destinationLimits := make(map[string]ratelimit.Limit)
+	for _, dest := range cfg.Webhook.Destinations {
+		provider, err := service.NewWebhookProvider(service.WebhookProviderOptions{
+			Name:         dest.Name,
+			URL:          dest.URL,
+			AuthKey:      dest.AuthKey,
+			MaxIdleConns: cfg.Scheduler.MaxInFlight,
+			Signer:       signer,
+			Mapping: service.WebhookMapping{
+				BodyTemplate: dest.BodyTemplate,
+				ContentType:  dest.ContentType,
+				RemoteIDPath: dest.RemoteIDPath,
+				SuccessPath:  dest.SuccessPath,
+				SuccessValue: dest.SuccessValue,
+			},
+		})
+		if err != nil {
+			log.Fatalf("configure webhook destination %s: %v", dest.Name, err)
+		}
+		destinations = append(destinations, service.Destination{
+			Provider: provider,
+			Priority: dest.Priority,
+			Weight:   dest.Weight,
+		})
+		if dest.RatePerSecond > 0 || dest.RateBurst > 0 {
+			destinationLimits[dest.Name] = ratelimit.Limit{
+				PerSecond: cmp.Or(dest.RatePerSecond, cfg.RateLimit.DestinationPerSecond),
+				Burst:     cmp.Or(dest.RateBurst, cfg.RateLimit.DestinationBurst),
+			}
+		}
+	}
+
+	routes := make([]service.Route, 0, len(cfg.Webhook.Routes))
+	for prefix, names := range cfg.Webhook.Routes {
+		routes = append(routes, service.Route{Prefix: prefix, Destinations: names})
+	}
+
+	var callbackVerifier *webhooksig.Verifier
+	var callbackNonces handler.NonceClaimer
+	if len(cfg.Callback.SigningSecrets) > 0 {
+		if callbackVerifier, err = webhooksig.NewVerifier(cfg.Callback.SigningSecrets, cfg.Callback.SignatureTolerance); err != nil {
+			log.Fatalf("configure callback verification: %v", err)
+		}
+		// Timestamps are accepted up to the tolerance on either side of now.
+		callbackNonces = service.NewNonceStore(redisClient, 2*cfg.Callback.SignatureTolerance)
+	} else {
+		log.Printf("WARNING: CALLBACK_SIGNING_SECRETS is not set; POST /api/v1/callbacks/delivery accepts unsigned delivery receipts from anyone who can reach it. Set it in every non-local environment.")
+	}
+
+	var quietHours *service.QuietHours
+	if cfg.Quiet.Enabled {
+		quietHours = &service.QuietHours{
+			Start:           cfg.Quiet.Start,
+			End:             cfg.Quiet.End,
+			DefaultTimezone: cfg.Quiet.DefaultTimezone,
+			Timezones:       cfg.Quiet.Timezones,
+		}
+	}
+
+	limiter := ratelimit.New(redisClient, ratelimit.Config{
+		Global:       ratelimit.Limit{PerSecond: cfg.RateLimit.GlobalPerSecond, Burst: cfg.RateLimit.GlobalBurst},
+		Destination:  ratelimit.Limit{PerSecond: cfg.RateLimit.DestinationPerSecond, Burst: cfg.RateLimit.DestinationBurst},
+		Destinations: destinationLimits,
+	})
+
 	messageService := service.NewMessageService(service.Dependencies{
-		Repo:  repo,
-		Redis: redisClient,
+		Repo:         repo,
+		Redis:        redisClient,
+		Limiter:      limiter,
+		Destinations: destinations,
 	}, service.MessageServiceOptions{
-		FetchLimit:     cfg.Scheduler.FetchLimit,
-		WebhookURL:     cfg.Webhook.URL,
-		WebhookAuthKey: cfg.Webhook.AuthKey,
+		FetchLimit:       cfg.Scheduler.FetchLimit,
+		LeaseOwner:       cfg.Scheduler.InstanceID,
+		LeaseDuration:    cfg.Scheduler.LeaseDuration,
+		MaxInFlight:      cfg.Scheduler.MaxInFlight,
+		LowPriorityShare: cfg.Scheduler.LowPriorityShare,
+		IterationTimeout: cfg.Scheduler.IterationTimeout,
+		Routes:           routes,
+		QuietHours:       quietHours,
+		SentMetadataTTL:  cfg.Redis.SentMetadataTTL,
+		RetryPolicy: service.RetryPolicy{
+			BaseDelay:   cfg.Retry.BaseDelay,
+			Multiplier:  cfg.Retry.Multiplier,
+			MaxDelay:    cfg.Retry.MaxDelay,
+			Jitter:      cfg.Retry.Jitter,
+			MaxAttempts: cfg.Retry.MaxAttempts,
+		},
+		CircuitBreaker: circuitbreaker.Settings{
+			Window:           cfg.Breaker.Window,
+			MinRequests:      cfg.Breaker.MinRequests,
+			FailureRatio:     cfg.Breaker.FailureRatio,
+			CoolDown:         cfg.Breaker.CoolDown,
+			HalfOpenRequests: cfg.Breaker.HalfOpenRequests,
+		},
 	})
 
 	schedLogger := log.New(os.Stdout, "scheduler ", log.LstdFlags)
 	sched := scheduler.New(messageService, cfg.Scheduler.Interval, schedLogger)
+	scheduleRepo := postgres.NewScheduleRepository(database)
+	sched.UseMaterializer(scheduler.NewMaterializer(scheduleRepo, cfg.Scheduler.Interval, schedLogger))
 
 	appCtx, cancel := context.WithCancel(context.Background())
 	defer cancel()
@@ -71,9 +172,11 @@ func main() {
 		log.Fatalf("start scheduler: %v", err)
 	}
 
-	controlHandler := handler.NewControlHandler(sched)
+	controlHandler := handler.NewControlHandler(sched, messageService, cfg.Server.ShutdownTimeout)
 	messageHandler := handler.NewMessageHandler(messageService)
-	router := httpserver.NewRouter(controlHandler, messageHandler)
+	scheduleHandler := handler.NewScheduleHandler(service.NewScheduleService(scheduleRepo, quietHours))
+	callbackHandler := handler.NewCallbackHandler(messageService, callbackVerifier, callbackNonces)
+	router := httpserver.NewRouter(controlHandler, messageHandler, scheduleHandler, callbackHandler)
 
 	server := &http.Server{
 		Addr:              ":" + cfg.HTTP.Port,
@@ -101,7 +204,8 @@ func main() {
 		log.Printf("server shutdown error: %v", err)
 	}
 
-	if err := sched.Stop(); err != nil && err != scheduler.ErrNotRunning {
+	// Let sends in flight record their outcome; shares the server's deadline.
+	if err := sched.Stop(shutdownCtx); err != nil && err != scheduler.ErrNotRunning {
 		log.Printf("scheduler stop error: %v", err)
 	}
 }
@@ -0,0 +1,71 @@
+// Command rehydrate rebuilds the Redis remote-id cache (sent_message:<id>
+// hashes) from the remote_message_id column in Postgres, e.g. after a Redis
+// flush or when the cache TTL was raised.
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"automessaging/internal/config"
+	dbpkg "automessaging/internal/db"
+	"automessaging/internal/repository/postgres"
+	"automessaging/internal/service"
+)
+
+func main() {
+	since := flag.String("since", "", "rebuild entries for messages sent at or after this RFC3339 time (default: now minus REDIS_SENT_METADATA_TTL, or everything when the TTL is 0)")
+	batch := flag.Int("batch", 500, "rows read from Postgres per query")
+	flag.Parse()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("load config: %v", err)
+	}
+
+	from := time.Time{}
+	switch {
+	case *since != "":
+		if from, err = time.Parse(time.RFC3339, *since); err != nil {
+			log.Fatalf("invalid -since: %v", err)
+		}
+	case cfg.Redis.SentMetadataTTL > 0:
+		from = time.Now().Add(-cfg.Redis.SentMetadataTTL)
+	}
+
+	database, err := dbpkg.Connect(cfg.Postgres)
+	if err != nil {
+		log.Fatalf("connect database: %v", err)
+	}
+	defer database.Close()
+
+	redisClient := redis.NewClient(&redis.Options{
+		Addr:     cfg.Redis.Addr,
+		Password: cfg.Redis.Password,
+		DB:       cfg.Redis.DB,
+	})
+	defer redisClient.Close()
+
+	messageService := service.NewMessageService(service.Dependencies{
+		Repo:  postgres.NewMessageRepository(database),
+		Redis: redisClient,
+	}, service.MessageServiceOptions{
+		SentMetadataTTL: cfg.Redis.SentMetadataTTL,
+	})
+
+	written, err := messageService.RehydrateSentMetadata(ctx, from, *batch)
+	if err != nil {
+		log.Fatalf("rehydrate after %d entries: %v", written, err)
+	}
+	log.Printf("rehydrated %d remote id mappings sent since %s", written, from.Format(time.RFC3339))
+}
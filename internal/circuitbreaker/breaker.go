@@ -0,0 +1,199 @@
+package circuitbreaker
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// State is the position of a circuit breaker.
+type State string
+
+const (
+	// StateClosed lets every call through while tracking failures.
+	StateClosed State = "closed"
+	// StateOpen rejects calls until the cool-down elapses.
+	StateOpen State = "open"
+	// StateHalfOpen lets a limited number of probe calls through.
+	StateHalfOpen State = "half-open"
+)
+
+// ErrOpen is returned by Allow while the breaker rejects calls.
+var ErrOpen = errors.New("circuit breaker is open")
+
+// Settings configures a Breaker.
+type Settings struct {
+	// Name identifies the protected dependency in snapshots.
+	Name string
+	// Window is how long failure counts accumulate while closed before they reset.
+	Window time.Duration
+	// MinRequests is the number of calls within a window before the failure ratio is evaluated.
+	MinRequests int
+	// FailureRatio trips the breaker when failures/calls reaches it.
+	FailureRatio float64
+	// CoolDown is how long the breaker stays open before allowing probes.
+	CoolDown time.Duration
+	// HalfOpenRequests is the number of probe calls allowed while half-open.
+	HalfOpenRequests int
+}
+
+// Snapshot is a point-in-time view of a breaker for reporting.
+type Snapshot struct {
+	Name      string     `json:"name"`
+	State     State      `json:"state"`
+	Requests  int        `json:"requests"`
+	Failures  int        `json:"failures"`
+	OpenUntil *time.Time `json:"open_until,omitempty"`
+}
+
+// Breaker is a closed/open/half-open circuit breaker safe for concurrent use.
+type Breaker struct {
+	settings Settings
+	now      func() time.Time
+
+	mu          sync.Mutex
+	state       State
+	requests    int
+	failures    int
+	windowStart time.Time
+	openedAt    time.Time
+	probes      int
+}
+
+// New builds a Breaker in the closed state.
+func New(settings Settings) *Breaker {
+	if settings.Window <= 0 {
+		settings.Window = time.Minute
+	}
+	if settings.MinRequests <= 0 {
+		settings.MinRequests = 5
+	}
+	if settings.FailureRatio <= 0 || settings.FailureRatio > 1 {
+		settings.FailureRatio = 0.5
+	}
+	if settings.CoolDown <= 0 {
+		settings.CoolDown = 30 * time.Second
+	}
+	if settings.HalfOpenRequests <= 0 {
+		settings.HalfOpenRequests = 1
+	}
+	return &Breaker{settings: settings, now: time.Now, state: StateClosed, windowStart: time.Now()}
+}
+
+// State returns the current state, moving an open breaker to half-open once
+// its cool-down has elapsed.
+func (b *Breaker) State() State {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.advance(b.now())
+	return b.state
+}
+
+// Allow reserves permission for one call. Every successful Allow must be
+// followed by exactly one Record.
+func (b *Breaker) Allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.advance(b.now())
+	switch b.state {
+	case StateOpen:
+		return ErrOpen
+	case StateHalfOpen:
+		if b.probes >= b.settings.HalfOpenRequests {
+			return ErrOpen
+		}
+		b.probes++
+	}
+	return nil
+}
+
+// Record reports the outcome of a call admitted by Allow.
+func (b *Breaker) Record(success bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.now()
+	b.advance(now)
+
+	switch b.state {
+	case StateHalfOpen:
+		if !success {
+			b.trip(now)
+			return
+		}
+		if b.probes > 0 {
+			b.probes--
+		}
+		b.requests++
+		if b.requests >= b.settings.HalfOpenRequests {
+			b.reset(now)
+		}
+	case StateClosed:
+		b.requests++
+		if !success {
+			b.failures++
+		}
+		if b.requests >= b.settings.MinRequests &&
+			float64(b.failures)/float64(b.requests) >= b.settings.FailureRatio {
+			b.trip(now)
+		}
+	}
+}
+
+// Discard releases a call admitted by Allow without recording an outcome,
+// e.g. when the caller gave up before the dependency answered.
+func (b *Breaker) Discard() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == StateHalfOpen && b.probes > 0 {
+		b.probes--
+	}
+}
+
+// Snapshot reports the breaker's current state.
+func (b *Breaker) Snapshot() Snapshot {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.advance(b.now())
+	snap := Snapshot{
+		Name:     b.settings.Name,
+		State:    b.state,
+		Requests: b.requests,
+		Failures: b.failures,
+	}
+	if b.state == StateOpen {
+		until := b.openedAt.Add(b.settings.CoolDown)
+		snap.OpenUntil = &until
+	}
+	return snap
+}
+
+func (b *Breaker) advance(now time.Time) {
+	switch b.state {
+	case StateOpen:
+		if now.Sub(b.openedAt) >= b.settings.CoolDown {
+			b.state = StateHalfOpen
+			b.requests, b.failures, b.probes = 0, 0, 0
+		}
+	case StateClosed:
+		if now.Sub(b.windowStart) >= b.settings.Window {
+			b.requests, b.failures = 0, 0
+			b.windowStart = now
+		}
+	}
+}
+
+func (b *Breaker) trip(now time.Time) {
+	b.state = StateOpen
+	b.openedAt = now
+	b.requests, b.failures, b.probes = 0, 0, 0
+}
+
+func (b *Breaker) reset(now time.Time) {
+	b.state = StateClosed
+	b.windowStart = now
+	b.requests, b.failures, b.probes = 0, 0, 0
+}
@@ -0,0 +1,85 @@
+package circuitbreaker
+
+import (
+	"testing"
+	"time"
+)
+
+func newTestBreaker(clock *time.Time) *Breaker {
+	b := New(Settings{Window: time.Minute, MinRequests: 4, FailureRatio: 0.5, CoolDown: 30 * time.Second, HalfOpenRequests: 1})
+	b.now = func() time.Time { return *clock }
+	b.windowStart = *clock
+	return b
+}
+
+func TestBreakerTripsOnFailureRatio(t *testing.T) {
+	clock := time.Date(2030, 1, 1, 9, 0, 0, 0, time.UTC)
+	b := newTestBreaker(&clock)
+
+	for _, success := range []bool{true, false, true} {
+		if err := b.Allow(); err != nil {
+			t.Fatalf("expected closed breaker to allow, got %v", err)
+		}
+		b.Record(success)
+	}
+	if b.State() != StateClosed {
+		t.Fatalf("expected breaker to stay closed below MinRequests, got %s", b.State())
+	}
+
+	_ = b.Allow()
+	b.Record(false)
+	if b.State() != StateOpen {
+		t.Fatalf("expected breaker to open at 50%% failures, got %s", b.State())
+	}
+	if err := b.Allow(); err != ErrOpen {
+		t.Fatalf("expected ErrOpen, got %v", err)
+	}
+}
+
+func TestBreakerHalfOpenProbe(t *testing.T) {
+	clock := time.Date(2030, 1, 1, 9, 0, 0, 0, time.UTC)
+	b := newTestBreaker(&clock)
+	for i := 0; i < 4; i++ {
+		_ = b.Allow()
+		b.Record(false)
+	}
+
+	clock = clock.Add(30 * time.Second)
+	if b.State() != StateHalfOpen {
+		t.Fatalf("expected half-open after cool-down, got %s", b.State())
+	}
+	if err := b.Allow(); err != nil {
+		t.Fatalf("expected probe to be allowed, got %v", err)
+	}
+	if err := b.Allow(); err != ErrOpen {
+		t.Fatalf("expected second probe to be rejected, got %v", err)
+	}
+
+	b.Record(false)
+	if b.State() != StateOpen {
+		t.Fatalf("expected failed probe to reopen, got %s", b.State())
+	}
+
+	clock = clock.Add(30 * time.Second)
+	_ = b.Allow()
+	b.Record(true)
+	if b.State() != StateClosed {
+		t.Fatalf("expected successful probe to close, got %s", b.State())
+	}
+}
+
+func TestBreakerWindowResetsCounts(t *testing.T) {
+	clock := time.Date(2030, 1, 1, 9, 0, 0, 0, time.UTC)
+	b := newTestBreaker(&clock)
+	for i := 0; i < 3; i++ {
+		_ = b.Allow()
+		b.Record(false)
+	}
+
+	clock = clock.Add(time.Minute)
+	_ = b.Allow()
+	b.Record(false)
+	if b.State() != StateClosed {
+		t.Fatalf("expected failures from an old window to be forgotten, got %s", b.State())
+	}
+}
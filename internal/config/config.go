@@ -1,10 +1,16 @@
 package config
 
 import (
+	"cmp"
+	"encoding/json"
 	"fmt"
+	"net/url"
 	"os"
 	"strconv"
+	"strings"
 	"time"
+
+	"automessaging/internal/timezone"
 )
 
 // Config captures all runtime configuration for the service.
@@ -14,6 +20,11 @@ type Config struct {
 	Redis     RedisConfig
 	Scheduler SchedulerConfig
 	Webhook   WebhookConfig
+	Callback  CallbackConfig
+	Retry     RetryConfig
+	RateLimit RateLimitConfig
+	Breaker   CircuitBreakerConfig
+	Quiet     QuietHoursConfig
 	Server    ServerConfig
 }
 
@@ -37,23 +48,111 @@ func (p PostgresConfig) DSN() string {
 	return fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s", p.Host, p.Port, p.User, p.Password, p.DBName, p.SSLMode)
 }
 
-// RedisConfig holds redis connection settings.
+// RedisConfig holds redis connection settings. SentMetadataTTL expires the
+// cached remote id mappings; zero keeps them forever.
 type RedisConfig struct {
-	Addr     string
-	Password string
-	DB       int
+	Addr            string
+	Password        string
+	DB              int
+	SentMetadataTTL time.Duration
 }
 
 // SchedulerConfig holds scheduling settings.
 type SchedulerConfig struct {
-	Interval   time.Duration
-	FetchLimit int
+	Interval         time.Duration
+	FetchLimit       int
+	InstanceID       string
+	LeaseDuration    time.Duration
+	MaxInFlight      int
+	IterationTimeout time.Duration
+	LowPriorityShare float64
 }
 
-// WebhookConfig stores outbound webhook details.
+// WebhookConfig stores outbound webhook details. SigningSecrets lists the
+// active HMAC secrets; the first is the newest and all are used while rotating.
+// The mapping fields describe the gateway's request and response formats and
+// are the defaults for every destination. Destinations always holds at least
+// the WEBHOOK_URL destination when one is configured. Routes maps recipient
+// prefixes to the names of the destinations allowed to serve them.
 type WebhookConfig struct {
-	URL     string
-	AuthKey string
+	URL            string
+	AuthKey        string
+	SigningSecrets []string
+	BodyTemplate   string
+	ContentType    string
+	RemoteIDPath   string
+	SuccessPath    string
+	SuccessValue   string
+	Destinations   []WebhookDestination
+	Routes         map[string][]string
+}
+
+// WebhookDestination is one gateway messages can be routed to. Lower Priority
+// values are preferred and Weight splits traffic within a priority. Empty
+// mapping fields inherit the WEBHOOK_* defaults, and a zero RatePerSecond or
+// RateBurst inherits the RATE_LIMIT_DESTINATION_* one.
+type WebhookDestination struct {
+	Name         string `json:"name"`
+	URL          string `json:"url"`
+	AuthKey      string `json:"auth_key"`
+	Priority     int    `json:"priority"`
+	Weight       int    `json:"weight"`
+	BodyTemplate string `json:"body_template"`
+	ContentType  string `json:"content_type"`
+	RemoteIDPath string `json:"remote_id_path"`
+	SuccessPath  string `json:"success_path"`
+	SuccessValue string `json:"success_value"`
+
+	RatePerSecond float64 `json:"rate_per_second"`
+	RateBurst     int     `json:"rate_burst"`
+}
+
+// CallbackConfig secures inbound provider callbacks. When SigningSecrets is
+// empty callbacks are accepted without a signature and the API logs a warning
+// at startup; that is only meant for local development.
+type CallbackConfig struct {
+	SigningSecrets     []string
+	SignatureTolerance time.Duration
+}
+
+// QuietHoursConfig is a daily window, in each recipient's local time, during
+// which messages are held back. Start and End are offsets from local
+// midnight; a Start after End wraps past midnight. Recipients without a known
+// time zone use DefaultTimezone, and Timezones maps recipient prefixes to the
+// zone inferred for them. The window is disabled when Enabled is false.
+type QuietHoursConfig struct {
+	Enabled         bool
+	Start           time.Duration
+	End             time.Duration
+	DefaultTimezone string
+	Timezones       map[string]string
+}
+
+// RetryConfig controls exponential backoff for failed deliveries.
+type RetryConfig struct {
+	BaseDelay   time.Duration
+	Multiplier  float64
+	MaxDelay    time.Duration
+	Jitter      float64
+	MaxAttempts int
+}
+
+// RateLimitConfig caps outbound webhook throughput across all replicas. A
+// zero rate disables the corresponding limit.
+type RateLimitConfig struct {
+	GlobalPerSecond      float64
+	GlobalBurst          int
+	DestinationPerSecond float64
+	DestinationBurst     int
+}
+
+// CircuitBreakerConfig configures the breaker around webhook calls.
+type CircuitBreakerConfig struct {
+	Window           time.Duration
+	MinRequests      int
+	FailureRatio     float64
+	CoolDown         time.Duration
+	HalfOpenRequests int
 }
 
 // ServerConfig stores general server runtime configuration.
@@ -73,6 +172,14 @@ func Load() (*Config, error) {
 		return nil, fmt.Errorf("invalid REDIS_DB: %w", err)
 	}
 
+	sentMetadataTTL, err := getDuration("REDIS_SENT_METADATA_TTL", 30*24*time.Hour)
+	if err != nil {
+		return nil, fmt.Errorf("invalid REDIS_SENT_METADATA_TTL: %w", err)
+	}
+	if sentMetadataTTL < 0 {
+		return nil, fmt.Errorf("invalid REDIS_SENT_METADATA_TTL: must not be negative")
+	}
+
 	fetchLimit, err := getInt("SCHEDULER_FETCH_LIMIT", 2)
 	if err != nil {
 		return nil, fmt.Errorf("invalid SCHEDULER_FETCH_LIMIT: %w", err)
@@ -87,6 +194,69 @@ func Load() (*Config, error) {
 		interval = 2 * time.Minute
 	}
 
+	leaseDurationStr := getString("SCHEDULER_LEASE_DURATION", "5m")
+	leaseDuration, err := time.ParseDuration(leaseDurationStr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid SCHEDULER_LEASE_DURATION: %w", err)
+	}
+	if leaseDuration <= 0 {
+		return nil, fmt.Errorf("invalid SCHEDULER_LEASE_DURATION: must be positive")
+	}
+
+	retryCfg, err := loadRetryConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	maxInFlight, err := getInt("SCHEDULER_MAX_IN_FLIGHT", 4)
+	if err != nil {
+		return nil, fmt.Errorf("invalid SCHEDULER_MAX_IN_FLIGHT: %w", err)
+	}
+	if maxInFlight < 1 {
+		return nil, fmt.Errorf("invalid SCHEDULER_MAX_IN_FLIGHT: must be at least 1")
+	}
+
+	iterationTimeout, err := getDuration("SCHEDULER_ITERATION_TIMEOUT", time.Minute)
+	if err != nil {
+		return nil, fmt.Errorf("invalid SCHEDULER_ITERATION_TIMEOUT: %w", err)
+	}
+	if iterationTimeout >= leaseDuration {
+		return nil, fmt.Errorf("invalid SCHEDULER_ITERATION_TIMEOUT: must be shorter than SCHEDULER_LEASE_DURATION")
+	}
+
+	lowPriorityShare, err := getFloat("SCHEDULER_LOW_PRIORITY_SHARE", 0)
+	if err != nil {
+		return nil, fmt.Errorf("invalid SCHEDULER_LOW_PRIORITY_SHARE: %w", err)
+	}
+	if lowPriorityShare < 0 || lowPriorityShare >= 1 {
+		return nil, fmt.Errorf("invalid SCHEDULER_LOW_PRIORITY_SHARE: must be at least 0 and below 1")
+	}
+
+	rateLimitCfg, err := loadRateLimitConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	breakerCfg, err := loadCircuitBreakerConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	webhookCfg, err := loadWebhookConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	quietCfg, err := loadQuietHoursConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	callbackTolerance, err := getDuration("CALLBACK_SIGNATURE_TOLERANCE", 5*time.Minute)
+	if err != nil {
+		return nil, fmt.Errorf("invalid CALLBACK_SIGNATURE_TOLERANCE: %w", err)
+	}
+
 	shutdownTimeoutStr := getString("SERVER_SHUTDOWN_TIMEOUT", "10s")
 	shutdownTimeout, err := time.ParseDuration(shutdownTimeoutStr)
 	if err != nil {
@@ -106,18 +276,29 @@ func Load() (*Config, error) {
 			SSLMode:  getString("POSTGRES_SSLMODE", "disable"),
 		},
 		Redis: RedisConfig{
-			Addr:     getString("REDIS_ADDR", "redis:6379"),
-			Password: getString("REDIS_PASSWORD", ""),
-			DB:       redisDB,
+			Addr:            getString("REDIS_ADDR", "redis:6379"),
+			Password:        getString("REDIS_PASSWORD", ""),
+			DB:              redisDB,
+			SentMetadataTTL: sentMetadataTTL,
 		},
 		Scheduler: SchedulerConfig{
-			Interval:   interval,
-			FetchLimit: fetchLimit,
+			Interval:         interval,
+			FetchLimit:       fetchLimit,
+			InstanceID:       getString("SCHEDULER_INSTANCE_ID", ""),
+			LeaseDuration:    leaseDuration,
+			MaxInFlight:      maxInFlight,
+			IterationTimeout: iterationTimeout,
+			LowPriorityShare: lowPriorityShare,
 		},
-		Webhook: WebhookConfig{
-			URL:     getString("WEBHOOK_URL", ""),
-			AuthKey: getString("WEBHOOK_AUTH_KEY", "INS.me1x9uMcyYGlhKKQVPoc.bO3j9aZwRTOcA2Ywo"),
+		Webhook: webhookCfg,
+		Callback: CallbackConfig{
+			SigningSecrets:     getList("CALLBACK_SIGNING_SECRETS"),
+			SignatureTolerance: callbackTolerance,
 		},
+		Retry:     retryCfg,
+		RateLimit: rateLimitCfg,
+		Breaker:   breakerCfg,
+		Quiet:     quietCfg,
 		Server: ServerConfig{
 			ShutdownTimeout: shutdownTimeout,
 		},
@@ -126,6 +307,247 @@ func Load() (*Config, error) {
 	return cfg, nil
 }
 
+func loadRetryConfig() (RetryConfig, error) {
+	baseDelay, err := getDuration("RETRY_BASE_DELAY", 30*time.Second)
+	if err != nil {
+		return RetryConfig{}, fmt.Errorf("invalid RETRY_BASE_DELAY: %w", err)
+	}
+
+	multiplier, err := getFloat("RETRY_MULTIPLIER", 2)
+	if err != nil {
+		return RetryConfig{}, fmt.Errorf("invalid RETRY_MULTIPLIER: %w", err)
+	}
+	if multiplier < 1 {
+		return RetryConfig{}, fmt.Errorf("invalid RETRY_MULTIPLIER: must be at least 1")
+	}
+
+	maxDelay, err := getDuration("RETRY_MAX_DELAY", time.Hour)
+	if err != nil {
+		return RetryConfig{}, fmt.Errorf("invalid RETRY_MAX_DELAY: %w", err)
+	}
+
+	jitter, err := getFloat("RETRY_JITTER", 0.2)
+	if err != nil {
+		return RetryConfig{}, fmt.Errorf("invalid RETRY_JITTER: %w", err)
+	}
+	if jitter < 0 || jitter > 1 {
+		return RetryConfig{}, fmt.Errorf("invalid RETRY_JITTER: must be between 0 and 1")
+	}
+
+	maxAttempts, err := getInt("RETRY_MAX_ATTEMPTS", 5)
+	if err != nil {
+		return RetryConfig{}, fmt.Errorf("invalid RETRY_MAX_ATTEMPTS: %w", err)
+	}
+	if maxAttempts < 1 {
+		return RetryConfig{}, fmt.Errorf("invalid RETRY_MAX_ATTEMPTS: must be at least 1")
+	}
+
+	return RetryConfig{
+		BaseDelay:   baseDelay,
+		Multiplier:  multiplier,
+		MaxDelay:    maxDelay,
+		Jitter:      jitter,
+		MaxAttempts: maxAttempts,
+	}, nil
+}
+
+func loadRateLimitConfig() (RateLimitConfig, error) {
+	globalRate, err := getFloat("RATE_LIMIT_GLOBAL_PER_SECOND", 0)
+	if err != nil {
+		return RateLimitConfig{}, fmt.Errorf("invalid RATE_LIMIT_GLOBAL_PER_SECOND: %w", err)
+	}
+
+	globalBurst, err := getInt("RATE_LIMIT_GLOBAL_BURST", 1)
+	if err != nil {
+		return RateLimitConfig{}, fmt.Errorf("invalid RATE_LIMIT_GLOBAL_BURST: %w", err)
+	}
+
+	destinationRate, err := getFloat("RATE_LIMIT_DESTINATION_PER_SECOND", 0)
+	if err != nil {
+		return RateLimitConfig{}, fmt.Errorf("invalid RATE_LIMIT_DESTINATION_PER_SECOND: %w", err)
+	}
+
+	destinationBurst, err := getInt("RATE_LIMIT_DESTINATION_BURST", 1)
+	if err != nil {
+		return RateLimitConfig{}, fmt.Errorf("invalid RATE_LIMIT_DESTINATION_BURST: %w", err)
+	}
+
+	if globalRate < 0 || destinationRate < 0 {
+		return RateLimitConfig{}, fmt.Errorf("invalid rate limit: rates must not be negative")
+	}
+
+	return RateLimitConfig{
+		GlobalPerSecond:      globalRate,
+		GlobalBurst:          globalBurst,
+		DestinationPerSecond: destinationRate,
+		DestinationBurst:     destinationBurst,
+	}, nil
+}
+
+func loadCircuitBreakerConfig() (CircuitBreakerConfig, error) {
+	window, err := getDuration("CIRCUIT_BREAKER_WINDOW", time.Minute)
+	if err != nil {
+		return CircuitBreakerConfig{}, fmt.Errorf("invalid CIRCUIT_BREAKER_WINDOW: %w", err)
+	}
+
+	minRequests, err := getInt("CIRCUIT_BREAKER_MIN_REQUESTS", 5)
+	if err != nil {
+		return CircuitBreakerConfig{}, fmt.Errorf("invalid CIRCUIT_BREAKER_MIN_REQUESTS: %w", err)
+	}
+
+	failureRatio, err := getFloat("CIRCUIT_BREAKER_FAILURE_RATIO", 0.5)
+	if err != nil {
+		return CircuitBreakerConfig{}, fmt.Errorf("invalid CIRCUIT_BREAKER_FAILURE_RATIO: %w", err)
+	}
+	if failureRatio <= 0 || failureRatio > 1 {
+		return CircuitBreakerConfig{}, fmt.Errorf("invalid CIRCUIT_BREAKER_FAILURE_RATIO: must be in (0, 1]")
+	}
+
+	coolDown, err := getDuration("CIRCUIT_BREAKER_COOLDOWN", 30*time.Second)
+	if err != nil {
+		return CircuitBreakerConfig{}, fmt.Errorf("invalid CIRCUIT_BREAKER_COOLDOWN: %w", err)
+	}
+
+	halfOpenRequests, err := getInt("CIRCUIT_BREAKER_HALF_OPEN_REQUESTS", 1)
+	if err != nil {
+		return CircuitBreakerConfig{}, fmt.Errorf("invalid CIRCUIT_BREAKER_HALF_OPEN_REQUESTS: %w", err)
+	}
+
+	return CircuitBreakerConfig{
+		Window:           window,
+		MinRequests:      minRequests,
+		FailureRatio:     failureRatio,
+		CoolDown:         coolDown,
+		HalfOpenRequests: halfOpenRequests,
+	}, nil
+}
+
+func loadWebhookConfig() (WebhookConfig, error) {
+	cfg := WebhookConfig{
+		URL:            getString("WEBHOOK_URL", ""),
+		AuthKey:        getString("WEBHOOK_AUTH_KEY", ""),
+		SigningSecrets: getList("WEBHOOK_SIGNING_SECRETS"),
+		BodyTemplate:   getString("WEBHOOK_BODY_TEMPLATE", `{"to":{{json .To}},"content":{{json .Content}}}`),
+		ContentType:    getString("WEBHOOK_CONTENT_TYPE", "application/json"),
+		RemoteIDPath:   getString("WEBHOOK_REMOTE_ID_PATH", "messageId"),
+		SuccessPath:    getString("WEBHOOK_SUCCESS_PATH", "message"),
+		SuccessValue:   getString("WEBHOOK_SUCCESS_VALUE", "Accepted"),
+	}
+
+	if raw := strings.TrimSpace(os.Getenv("WEBHOOK_DESTINATIONS")); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &cfg.Destinations); err != nil {
+			return WebhookConfig{}, fmt.Errorf("invalid WEBHOOK_DESTINATIONS: %w", err)
+		}
+	}
+	if cfg.URL != "" {
+		cfg.Destinations = append([]WebhookDestination{{URL: cfg.URL, AuthKey: cfg.AuthKey}}, cfg.Destinations...)
+	}
+
+	names := make(map[string]bool, len(cfg.Destinations))
+	for i := range cfg.Destinations {
+		dest := &cfg.Destinations[i]
+		if dest.URL == "" {
+			return WebhookConfig{}, fmt.Errorf("invalid WEBHOOK_DESTINATIONS: destination %d has no url", i)
+		}
+		if dest.Weight < 0 {
+			return WebhookConfig{}, fmt.Errorf("invalid WEBHOOK_DESTINATIONS: weight of %s must not be negative", dest.URL)
+		}
+		if dest.Weight == 0 {
+			dest.Weight = 1
+		}
+		if dest.RatePerSecond < 0 || dest.RateBurst < 0 {
+			return WebhookConfig{}, fmt.Errorf("invalid WEBHOOK_DESTINATIONS: rate limit of %s must not be negative", dest.URL)
+		}
+		if dest.Name == "" {
+			dest.Name = hostOf(dest.URL)
+		}
+		if names[dest.Name] {
+			return WebhookConfig{}, fmt.Errorf("invalid WEBHOOK_DESTINATIONS: duplicate destination name %q", dest.Name)
+		}
+		names[dest.Name] = true
+
+		dest.BodyTemplate = cmp.Or(dest.BodyTemplate, cfg.BodyTemplate)
+		dest.ContentType = cmp.Or(dest.ContentType, cfg.ContentType)
+		dest.RemoteIDPath = cmp.Or(dest.RemoteIDPath, cfg.RemoteIDPath)
+		dest.SuccessPath = cmp.Or(dest.SuccessPath, cfg.SuccessPath)
+		dest.SuccessValue = cmp.Or(dest.SuccessValue, cfg.SuccessValue)
+	}
+
+	if raw := strings.TrimSpace(os.Getenv("WEBHOOK_ROUTES")); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &cfg.Routes); err != nil {
+			return WebhookConfig{}, fmt.Errorf("invalid WEBHOOK_ROUTES: %w", err)
+		}
+	}
+	for prefix, targets := range cfg.Routes {
+		if len(targets) == 0 {
+			return WebhookConfig{}, fmt.Errorf("invalid WEBHOOK_ROUTES: route %q has no destinations", prefix)
+		}
+		for _, name := range targets {
+			if !names[name] {
+				return WebhookConfig{}, fmt.Errorf("invalid WEBHOOK_ROUTES: route %q references unknown destination %q", prefix, name)
+			}
+		}
+	}
+
+	return cfg, nil
+}
+
+func loadQuietHoursConfig() (QuietHoursConfig, error) {
+	cfg := QuietHoursConfig{DefaultTimezone: getString("QUIET_HOURS_DEFAULT_TIMEZONE", "UTC")}
+	if _, err := timezone.Load(cfg.DefaultTimezone); err != nil {
+		return QuietHoursConfig{}, fmt.Errorf("invalid QUIET_HOURS_DEFAULT_TIMEZONE: %q %w", cfg.DefaultTimezone, err)
+	}
+
+	if raw := strings.TrimSpace(os.Getenv("QUIET_HOURS_TIMEZONES")); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &cfg.Timezones); err != nil {
+			return QuietHoursConfig{}, fmt.Errorf("invalid QUIET_HOURS_TIMEZONES: %w", err)
+		}
+	}
+	for prefix, zone := range cfg.Timezones {
+		if _, err := timezone.Load(zone); err != nil {
+			return QuietHoursConfig{}, fmt.Errorf("invalid QUIET_HOURS_TIMEZONES: prefix %q: %q %w", prefix, zone, err)
+		}
+	}
+
+	raw := strings.TrimSpace(os.Getenv("QUIET_HOURS"))
+	if raw == "" {
+		return cfg, nil
+	}
+	rawStart, rawEnd, ok := strings.Cut(raw, "-")
+	if !ok {
+		return QuietHoursConfig{}, fmt.Errorf("invalid QUIET_HOURS: expected HH:MM-HH:MM")
+	}
+	var err error
+	if cfg.Start, err = parseClock(rawStart); err != nil {
+		return QuietHoursConfig{}, fmt.Errorf("invalid QUIET_HOURS: %w", err)
+	}
+	if cfg.End, err = parseClock(rawEnd); err != nil {
+		return QuietHoursConfig{}, fmt.Errorf("invalid QUIET_HOURS: %w", err)
+	}
+	if cfg.Start == cfg.End {
+		return QuietHoursConfig{}, fmt.Errorf("invalid QUIET_HOURS: start and end must differ")
+	}
+	cfg.Enabled = true
+	return cfg, nil
+}
+
+// parseClock parses an HH:MM wall-clock time into an offset from midnight.
+func parseClock(raw string) (time.Duration, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(raw))
+	if err != nil {
+		return 0, fmt.Errorf("%q is not an HH:MM time", raw)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// hostOf names a destination after its host when no name is configured.
+func hostOf(rawURL string) string {
+	if parsed, err := url.Parse(rawURL); err == nil && parsed.Host != "" {
+		return parsed.Host
+	}
+	return rawURL
+}
+
 func getString(key, def string) string {
 	if val := os.Getenv(key); val != "" {
 		return val
@@ -133,6 +555,16 @@ func getString(key, def string) string {
 	return def
 }
 
+func getList(key string) []string {
+	var items []string
+	for _, item := range strings.Split(os.Getenv(key), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
 func getInt(key string, def int) (int, error) {
 	if val := os.Getenv(key); val != "" {
 		parsed, err := strconv.Atoi(val)
@@ -143,3 +575,17 @@ func getInt(key string, def int) (int, error) {
 	}
 	return def, nil
 }
+
+func getFloat(key string, def float64) (float64, error) {
+	if val := os.Getenv(key); val != "" {
+		return strconv.ParseFloat(val, 64)
+	}
+	return def, nil
+}
+
+func getDuration(key string, def time.Duration) (time.Duration, error) {
+	if val := os.Getenv(key); val != "" {
+		return time.ParseDuration(val)
+	}
+	return def, nil
+}
@@ -19,3 +19,85 @@ func TestSchedulerIntervalClamp(t *testing.T) {
 		t.Fatalf("expected interval to be clamped to 2m, got %v", cfg.Scheduler.Interval)
 	}
 }
+
+func TestWebhookDestinations(t *testing.T) {
+	t.Setenv("WEBHOOK_URL", "https://primary.example.com/send")
+	t.Setenv("WEBHOOK_DESTINATIONS", `[{"name":"backup","url":"https://backup.example.com/sms","priority":1,"success_value":"OK","rate_per_second":2.5,"rate_burst":5}]`)
+	t.Setenv("WEBHOOK_ROUTES", `{"+1":["backup"]}`)
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("load config: %v", err)
+	}
+
+	dests := cfg.Webhook.Destinations
+	if len(dests) != 2 {
+		t.Fatalf("expected 2 destinations, got %d", len(dests))
+	}
+	if dests[0].Name != "primary.example.com" || dests[0].Weight != 1 || dests[0].SuccessValue != "Accepted" {
+		t.Fatalf("unexpected primary destination: %+v", dests[0])
+	}
+	if dests[1].Name != "backup" || dests[1].Priority != 1 || dests[1].SuccessValue != "OK" || dests[1].RemoteIDPath != "messageId" ||
+		dests[1].RatePerSecond != 2.5 || dests[1].RateBurst != 5 {
+		t.Fatalf("unexpected backup destination: %+v", dests[1])
+	}
+}
+
+func TestWebhookRoutesRejectUnknownDestination(t *testing.T) {
+	t.Setenv("WEBHOOK_URL", "https://primary.example.com/send")
+	t.Setenv("WEBHOOK_ROUTES", `{"+90":["turkey"]}`)
+
+	if _, err := Load(); err == nil {
+		t.Fatal("expected an error for a route to an unknown destination")
+	}
+}
+
+func TestWebhookDestinationsRejectNegativeRate(t *testing.T) {
+	t.Setenv("WEBHOOK_DESTINATIONS", `[{"name":"backup","url":"https://backup.example.com/sms","rate_per_second":-1}]`)
+
+	if _, err := Load(); err == nil {
+		t.Fatal("expected an error for a negative destination rate")
+	}
+}
+
+func TestQuietHours(t *testing.T) {
+	t.Setenv("QUIET_HOURS", "21:00-08:00")
+	t.Setenv("QUIET_HOURS_DEFAULT_TIMEZONE", "Europe/Istanbul")
+	t.Setenv("QUIET_HOURS_TIMEZONES", `{"+1":"America/New_York"}`)
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("load config: %v", err)
+	}
+
+	quiet := cfg.Quiet
+	if !quiet.Enabled || quiet.Start != 21*time.Hour || quiet.End != 8*time.Hour {
+		t.Fatalf("unexpected quiet hours: %+v", quiet)
+	}
+	if quiet.DefaultTimezone != "Europe/Istanbul" || quiet.Timezones["+1"] != "America/New_York" {
+		t.Fatalf("unexpected quiet hours time zones: %+v", quiet)
+	}
+}
+
+func TestQuietHoursRejectInvalid(t *testing.T) {
+	cases := map[string]map[string]string{
+		"empty window":     {"QUIET_HOURS": "08:00-08:00"},
+		"malformed window": {"QUIET_HOURS": "21:00"},
+		"bad clock":        {"QUIET_HOURS": "25:00-08:00"},
+		"unknown default":  {"QUIET_HOURS_DEFAULT_TIMEZONE": "Mars/Olympus"},
+		"unknown prefix":   {"QUIET_HOURS_TIMEZONES": `{"+90":"Mars/Olympus"}`},
+		"local default":    {"QUIET_HOURS_DEFAULT_TIMEZONE": "Local"},
+		"local prefix":     {"QUIET_HOURS_TIMEZONES": `{"+90":"Local"}`},
+	}
+
+	for name, env := range cases {
+		t.Run(name, func(t *testing.T) {
+			for key, value := range env {
+				t.Setenv(key, value)
+			}
+			if _, err := Load(); err == nil {
+				t.Fatal("expected an error")
+			}
+		})
+	}
+}
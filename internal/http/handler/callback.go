@@ -0,0 +1,87 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+
+	"automessaging/internal/service"
+	"automessaging/pkg/webhooksig"
+)
+
+// ReceiptRecorder applies provider delivery receipts.
+type ReceiptRecorder interface {
+	RecordDeliveryReceipt(ctx context.Context, input service.DeliveryReceiptInput) (service.DeliveryReceiptResult, error)
+}
+
+// NonceClaimer remembers callback nonces so signed requests cannot be replayed.
+type NonceClaimer interface {
+	Claim(ctx context.Context, nonce string) (bool, error)
+	Release(ctx context.Context, nonce string) error
+}
+
+// CallbackHandler receives inbound provider callbacks.
+type CallbackHandler struct {
+	receipts ReceiptRecorder
+	verifier *webhooksig.Verifier
+	nonces   NonceClaimer
+}
+
+// NewCallbackHandler builds a CallbackHandler. When verifier is non-nil every
+// callback must carry a valid signature, and when nonces is also set each
+// signed request is applied at most once.
+func NewCallbackHandler(receipts ReceiptRecorder, verifier *webhooksig.Verifier, nonces NonceClaimer) *CallbackHandler {
+	return &CallbackHandler{receipts: receipts, verifier: verifier, nonces: nonces}
+}
+
+// Delivery handles POST /callbacks/delivery. Receipts for messages that are
+// not marked as sent yet return 404 so the provider retries them later; the
+// nonce of a receipt that was not applied is released so that retry is not
+// mistaken for a replay.
+func (h *CallbackHandler) Delivery(w http.ResponseWriter, r *http.Request) {
+	var nonce string
+	if h.verifier != nil {
+		r.Body = http.MaxBytesReader(w, r.Body, maxRequestBodyBytes)
+		if err := h.verifier.VerifyRequest(r); err != nil {
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": err.Error()})
+			return
+		}
+
+		if h.nonces != nil {
+			nonce = r.Header.Get(webhooksig.HeaderNonce)
+			fresh, err := h.nonces.Claim(r.Context(), nonce)
+			if err != nil {
+				writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "replay protection unavailable"})
+				return
+			}
+			if !fresh {
+				writeJSON(w, http.StatusUnauthorized, map[string]string{"error": webhooksig.ErrReplayed.Error()})
+				return
+			}
+		}
+	}
+
+	var input service.DeliveryReceiptInput
+	if err := decodeJSON(w, r, &input); err != nil {
+		h.releaseNonce(r.Context(), nonce)
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+
+	result, err := h.receipts.RecordDeliveryReceipt(r.Context(), input)
+	if err != nil {
+		h.releaseNonce(r.Context(), nonce)
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, result)
+}
+
+// releaseNonce forgets a claimed nonce. If that fails the retry is rejected
+// as a replay until the nonce expires, which the provider's backoff outlasts.
+func (h *CallbackHandler) releaseNonce(ctx context.Context, nonce string) {
+	if nonce == "" {
+		return
+	}
+	_ = h.nonces.Release(context.WithoutCancel(ctx), nonce)
+}
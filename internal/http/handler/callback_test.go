@@ -0,0 +1,104 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+
+	"automessaging/internal/model"
+	"automessaging/internal/service"
+	"automessaging/pkg/webhooksig"
+)
+
+func TestDeliveryRejectsReplayedCallback(t *testing.T) {
+	receipts := &stubReceipts{}
+	h := newTestCallbackHandler(t, receipts)
+	header := signedHeader(t, receiptBody)
+
+	if rec := postDelivery(h, header, receiptBody); rec.Code != http.StatusOK {
+		t.Fatalf("first request: status %d, body %s", rec.Code, rec.Body)
+	}
+	rec := postDelivery(h, header, receiptBody)
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("replay: status %d, want 401; body %s", rec.Code, rec.Body)
+	}
+	if receipts.calls != 1 {
+		t.Fatalf("recorded %d receipts, want 1", receipts.calls)
+	}
+}
+
+func TestDeliveryAllowsRetryOfUnappliedCallback(t *testing.T) {
+	receipts := &stubReceipts{err: service.ErrNotFound}
+	h := newTestCallbackHandler(t, receipts)
+	header := signedHeader(t, receiptBody)
+
+	if rec := postDelivery(h, header, receiptBody); rec.Code != http.StatusNotFound {
+		t.Fatalf("first request: status %d, want 404; body %s", rec.Code, rec.Body)
+	}
+	receipts.err = nil
+	if rec := postDelivery(h, header, receiptBody); rec.Code != http.StatusOK {
+		t.Fatalf("retry: status %d, want 200; body %s", rec.Code, rec.Body)
+	}
+	if receipts.calls != 2 {
+		t.Fatalf("recorded %d receipts, want 2", receipts.calls)
+	}
+}
+
+const receiptBody = `{"message_id":"remote-1","status":"delivered"}`
+
+type stubReceipts struct {
+	err   error
+	calls int
+}
+
+func (s *stubReceipts) RecordDeliveryReceipt(context.Context, service.DeliveryReceiptInput) (service.DeliveryReceiptResult, error) {
+	s.calls++
+	if s.err != nil {
+		return service.DeliveryReceiptResult{}, s.err
+	}
+	return service.DeliveryReceiptResult{ID: uuid.New(), Status: model.StatusDelivered, Applied: true}, nil
+}
+
+const testCallbackSecret = "callback-secret"
+
+func newTestCallbackHandler(t *testing.T, receipts ReceiptRecorder) *CallbackHandler {
+	t.Helper()
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	verifier, err := webhooksig.NewVerifier([]string{testCallbackSecret}, 5*time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewCallbackHandler(receipts, verifier, service.NewNonceStore(client, 10*time.Minute))
+}
+
+func signedHeader(t *testing.T, body string) http.Header {
+	t.Helper()
+	signer, err := webhooksig.NewSigner(testCallbackSecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	header := http.Header{}
+	if err := signer.Sign(header, []byte(body)); err != nil {
+		t.Fatal(err)
+	}
+	return header
+}
+
+func postDelivery(h *CallbackHandler, header http.Header, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/callbacks/delivery", bytes.NewBufferString(body))
+	req.Header = header.Clone()
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	h.Delivery(rec, req)
+	return rec
+}
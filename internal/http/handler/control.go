@@ -3,25 +3,49 @@ package handler
 import (
 	"context"
 	"net/http"
+	"time"
 
+	"automessaging/internal/circuitbreaker"
 	"automessaging/internal/scheduler"
 )
 
 // SchedulerController abstracts scheduler operations for handlers.
 type SchedulerController interface {
 	Start(ctx context.Context) error
-	Stop() error
+	Stop(ctx context.Context) error
 	IsRunning() bool
 }
 
+// CircuitReporter exposes the circuit breakers guarding outbound calls.
+type CircuitReporter interface {
+	CircuitBreakers() []circuitbreaker.Snapshot
+}
+
 // ControlHandler handles scheduler start/stop endpoints.
 type ControlHandler struct {
-	scheduler SchedulerController
+	scheduler   SchedulerController
+	circuits    CircuitReporter
+	stopTimeout time.Duration
+}
+
+// NewControlHandler creates a new instance. stopTimeout bounds how long Stop
+// waits for the running iteration before cancelling it.
+func NewControlHandler(s SchedulerController, circuits CircuitReporter, stopTimeout time.Duration) *ControlHandler {
+	return &ControlHandler{scheduler: s, circuits: circuits, stopTimeout: stopTimeout}
 }
 
-// NewControlHandler creates a new instance.
-func NewControlHandler(s SchedulerController) *ControlHandler {
-	return &ControlHandler{scheduler: s}
+type controlStatus struct {
+	Scheduler       string                    `json:"scheduler"`
+	CircuitBreakers []circuitbreaker.Snapshot `json:"circuit_breakers"`
+}
+
+// Status reports whether the scheduler is running and the circuit breaker states.
+func (h *ControlHandler) Status(w http.ResponseWriter, r *http.Request) {
+	status := controlStatus{Scheduler: "stopped", CircuitBreakers: h.circuits.CircuitBreakers()}
+	if h.scheduler.IsRunning() {
+		status.Scheduler = "running"
+	}
+	writeJSON(w, http.StatusOK, status)
 }
 
 // Start triggers the scheduler loop.
@@ -38,9 +62,13 @@ func (h *ControlHandler) Start(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, map[string]string{"status": "started"})
 }
 
-// Stop halts the scheduler loop.
+// Stop halts the scheduler loop once the current iteration has finished.
+// The wait is bounded by stopTimeout rather than the request, so a client
+// that disconnects cannot cancel sends in flight.
 func (h *ControlHandler) Stop(w http.ResponseWriter, r *http.Request) {
-	if err := h.scheduler.Stop(); err != nil {
+	ctx, cancel := context.WithTimeout(context.Background(), h.stopTimeout)
+	defer cancel()
+	if err := h.scheduler.Stop(ctx); err != nil {
 		status := http.StatusInternalServerError
 		if err == scheduler.ErrNotRunning {
 			status = http.StatusBadRequest
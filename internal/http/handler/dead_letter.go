@@ -0,0 +1,125 @@
+package handler
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+
+	"automessaging/internal/service"
+)
+
+// ListDeadLetters handles GET /messages/dead.
+func (h *MessageHandler) ListDeadLetters(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter, err := parseDeadLetterFilter(query)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+
+	page := parseIntDefault(query.Get("page"), 1)
+	limit := parseIntDefault(query.Get("limit"), 20)
+
+	result, err := h.svc.ListDeadLetters(r.Context(), filter, page, limit)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, result)
+}
+
+// Requeue handles POST /messages/{id}/requeue.
+func (h *MessageHandler) Requeue(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid message id"})
+		return
+	}
+
+	if err := h.svc.Requeue(r.Context(), id); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]string{"status": "queued"})
+}
+
+// RequeueDeadLetters handles POST /messages/dead/requeue.
+func (h *MessageHandler) RequeueDeadLetters(w http.ResponseWriter, r *http.Request) {
+	// The filter body is optional; chunked requests report no length, so an
+	// empty body is only known once decoding reaches EOF.
+	var filter service.DeadLetterFilter
+	if err := decodeJSON(w, r, &filter); err != nil && !errors.Is(err, io.EOF) {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+
+	requeued, err := h.svc.RequeueDeadLetters(r.Context(), filter, r.URL.Query().Get("all") == "true")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]int64{"requeued": requeued})
+}
+
+// PurgeDeadLetters handles DELETE /messages/dead.
+func (h *MessageHandler) PurgeDeadLetters(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter, err := parseDeadLetterFilter(query)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+
+	purged, err := h.svc.PurgeDeadLetters(r.Context(), filter, query.Get("all") == "true")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]int64{"purged": purged})
+}
+
+func parseDeadLetterFilter(query url.Values) (service.DeadLetterFilter, error) {
+	filter := service.DeadLetterFilter{
+		To:            query.Get("to"),
+		ErrorContains: query.Get("error_contains"),
+	}
+
+	for _, raw := range query["id"] {
+		id, err := uuid.Parse(raw)
+		if err != nil {
+			return service.DeadLetterFilter{}, errors.New("invalid id: " + raw)
+		}
+		filter.IDs = append(filter.IDs, id)
+	}
+
+	var err error
+	if filter.FailedAfter, err = parseTimeParam(query, "failed_after"); err != nil {
+		return service.DeadLetterFilter{}, err
+	}
+	if filter.FailedBefore, err = parseTimeParam(query, "failed_before"); err != nil {
+		return service.DeadLetterFilter{}, err
+	}
+
+	return filter, nil
+}
+
+func parseTimeParam(query url.Values, name string) (*time.Time, error) {
+	raw := query.Get(name)
+	if raw == "" {
+		return nil, nil
+	}
+	ts, err := time.Parse(time.RFC3339, raw)
+	if err != nil {
+		return nil, errors.New("invalid " + name + ": expected RFC3339 timestamp")
+	}
+	return &ts, nil
+}
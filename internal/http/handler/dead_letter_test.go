@@ -0,0 +1,42 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"automessaging/internal/repository"
+)
+
+func TestRequeueDeadLettersAcceptsEmptyChunkedBody(t *testing.T) {
+	for _, body := range []string{"", `{"to":"+905551112233"}`} {
+		repo := &requeueRepo{}
+		h := newTestMessageHandler(repo)
+
+		req := httptest.NewRequest(http.MethodPost, "/messages/dead/requeue?all=true", strings.NewReader(body))
+		req.ContentLength = -1
+		req.TransferEncoding = []string{"chunked"}
+		rec := httptest.NewRecorder()
+		h.RequeueDeadLetters(rec, req)
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("body %q: status %d, body %s", body, rec.Code, rec.Body)
+		}
+		if repo.calls != 1 {
+			t.Fatalf("body %q: requeued %d times, want 1", body, repo.calls)
+		}
+	}
+}
+
+// requeueRepo counts bulk requeues.
+type requeueRepo struct {
+	repository.MessageRepository
+	calls int
+}
+
+func (r *requeueRepo) RequeueDeadLetters(context.Context, repository.DeadLetterFilter) (int64, error) {
+	r.calls++
+	return 0, nil
+}
@@ -0,0 +1,75 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"automessaging/internal/model"
+	"automessaging/internal/service"
+)
+
+// ListSent handles GET /messages/sent.
+func (h *MessageHandler) ListSent(w http.ResponseWriter, r *http.Request) {
+	h.list(w, r, h.svc.ListSentMessages)
+}
+
+// ListPending handles GET /messages/pending.
+func (h *MessageHandler) ListPending(w http.ResponseWriter, r *http.Request) {
+	h.list(w, r, h.svc.ListPendingMessages)
+}
+
+// ListFailed handles GET /messages/failed.
+func (h *MessageHandler) ListFailed(w http.ResponseWriter, r *http.Request) {
+	h.list(w, r, h.svc.ListFailedMessages)
+}
+
+func (h *MessageHandler) list(w http.ResponseWriter, r *http.Request, fetch func(context.Context, service.MessageListQuery) (service.MessageListResult, error)) {
+	query, err := parseMessageListQuery(r.URL.Query())
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+
+	result, err := fetch(r.Context(), query)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, result)
+}
+
+func parseMessageListQuery(query url.Values) (service.MessageListQuery, error) {
+	listQuery := service.MessageListQuery{
+		To:              query.Get("to"),
+		ContentContains: query.Get("content_contains"),
+		Cursor:          query.Get("cursor"),
+		Page:            parseIntDefault(query.Get("page"), 1),
+		Limit:           parseIntDefault(query.Get("limit"), 20),
+	}
+
+	for _, status := range query["status"] {
+		listQuery.Statuses = append(listQuery.Statuses, model.MessageStatus(status))
+	}
+
+	if raw := query.Get("include_total"); raw != "" {
+		includeTotal, err := strconv.ParseBool(raw)
+		if err != nil {
+			return service.MessageListQuery{}, errors.New("invalid include_total: expected a boolean")
+		}
+		listQuery.IncludeTotal = includeTotal
+	}
+
+	var err error
+	if listQuery.From, err = parseTimeParam(query, "from"); err != nil {
+		return service.MessageListQuery{}, err
+	}
+	if listQuery.Until, err = parseTimeParam(query, "until"); err != nil {
+		return service.MessageListQuery{}, err
+	}
+
+	return listQuery, nil
+}
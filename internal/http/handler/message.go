@@ -2,38 +2,239 @@ package handler
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
 
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+
+	"automessaging/internal/model"
 	"automessaging/internal/service"
 )
 
+const maxRequestBodyBytes = 1 << 20
+
+// MessageService abstracts message operations for handlers.
+type MessageService interface {
+	CreateMessage(ctx context.Context, input service.CreateMessageInput) (model.Message, bool, error)
+	CreateMessages(ctx context.Context, inputs []service.CreateMessageInput, idempotencyKey string) (service.BatchCreateResult, bool, error)
+	ListSentMessages(ctx context.Context, query service.MessageListQuery) (service.MessageListResult, error)
+	ListPendingMessages(ctx context.Context, query service.MessageListQuery) (service.MessageListResult, error)
+	ListFailedMessages(ctx context.Context, query service.MessageListQuery) (service.MessageListResult, error)
+	GetMessage(ctx context.Context, id uuid.UUID) (model.Message, error)
+	GetMessageByRemoteID(ctx context.Context, remoteID string) (model.Message, error)
+	EditMessage(ctx context.Context, id uuid.UUID, version int, input service.EditMessageInput) (model.Message, error)
+	CancelMessage(ctx context.Context, id uuid.UUID, version int) (model.Message, error)
+	ListDeadLetters(ctx context.Context, filter service.DeadLetterFilter, page, limit int) (service.DeadLettersResult, error)
+	Requeue(ctx context.Context, id uuid.UUID) error
+	RequeueDeadLetters(ctx context.Context, filter service.DeadLetterFilter, all bool) (int64, error)
+	PurgeDeadLetters(ctx context.Context, filter service.DeadLetterFilter, all bool) (int64, error)
+}
+
 // MessageHandler provides HTTP endpoints for messages.
 type MessageHandler struct {
-	svc interface {
-		ListSentMessages(ctx context.Context, page, limit int) (service.SentMessagesResult, error)
-	}
+	svc MessageService
 }
 
 // NewMessageHandler builds a MessageHandler.
-func NewMessageHandler(svc interface {
-	ListSentMessages(ctx context.Context, page, limit int) (service.SentMessagesResult, error)
-}) *MessageHandler {
+func NewMessageHandler(svc MessageService) *MessageHandler {
 	return &MessageHandler{svc: svc}
 }
 
-// ListSent handles GET /messages/sent.
-func (h *MessageHandler) ListSent(w http.ResponseWriter, r *http.Request) {
-	page := parseIntDefault(r.URL.Query().Get("page"), 1)
-	limit := parseIntDefault(r.URL.Query().Get("limit"), 20)
+// Create handles POST /messages. Repeating a request with the same
+// Idempotency-Key header returns the original message instead of enqueuing a
+// duplicate.
+func (h *MessageHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var input service.CreateMessageInput
+	if err := decodeJSON(w, r, &input); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	input.IdempotencyKey = r.Header.Get("Idempotency-Key")
+
+	msg, created, err := h.svc.CreateMessage(r.Context(), input)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	status := http.StatusCreated
+	if !created {
+		status = http.StatusOK
+	}
+	writeJSON(w, status, msg)
+}
+
+// CreateBatch handles POST /messages/batch. Repeating a request with the same
+// Idempotency-Key header returns the original result instead of enqueuing the
+// batch again.
+func (h *MessageHandler) CreateBatch(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Messages []service.CreateMessageInput `json:"messages"`
+	}
+	if err := decodeJSON(w, r, &req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+
+	result, created, err := h.svc.CreateMessages(r.Context(), req.Messages, r.Header.Get("Idempotency-Key"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	status := http.StatusCreated
+	switch {
+	case result.Created == 0:
+		status = http.StatusBadRequest
+	case result.Failed > 0:
+		status = http.StatusMultiStatus
+	case !created:
+		status = http.StatusOK
+	}
+	writeJSON(w, status, result)
+}
+
+// Get handles GET /messages/{id}.
+func (h *MessageHandler) Get(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid message id"})
+		return
+	}
+
+	msg, err := h.svc.GetMessage(r.Context(), id)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("ETag", etag(msg.Version))
+	writeJSON(w, http.StatusOK, msg)
+}
+
+// Edit handles PATCH /messages/{id}. The If-Match header must carry the ETag
+// of the version being edited, so an edit cannot race the scheduler claiming
+// the message.
+func (h *MessageHandler) Edit(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid message id"})
+		return
+	}
+
+	if r.Header.Get("If-Match") == "" {
+		writeJSON(w, http.StatusPreconditionRequired, map[string]string{"error": "If-Match header is required"})
+		return
+	}
+	version, err := parseIfMatch(r)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+
+	var input service.EditMessageInput
+	if err := decodeJSON(w, r, &input); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+
+	msg, err := h.svc.EditMessage(r.Context(), id, version, input)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("ETag", etag(msg.Version))
+	writeJSON(w, http.StatusOK, msg)
+}
+
+// Cancel handles DELETE /messages/{id}. The message is kept with the
+// cancelled status; If-Match is optional.
+func (h *MessageHandler) Cancel(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid message id"})
+		return
+	}
+
+	var version int
+	if r.Header.Get("If-Match") != "" {
+		if version, err = parseIfMatch(r); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+	}
+
+	msg, err := h.svc.CancelMessage(r.Context(), id, version)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("ETag", etag(msg.Version))
+	writeJSON(w, http.StatusOK, msg)
+}
+
+// GetByRemoteID handles GET /messages/by-remote-id/{remoteID}.
+func (h *MessageHandler) GetByRemoteID(w http.ResponseWriter, r *http.Request) {
+	remoteID, err := url.PathUnescape(chi.URLParam(r, "remoteID"))
+	if err != nil || remoteID == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid remote id"})
+		return
+	}
 
-	result, err := h.svc.ListSentMessages(r.Context(), page, limit)
+	msg, err := h.svc.GetMessageByRemoteID(r.Context(), remoteID)
 	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		writeError(w, err)
 		return
 	}
 
-	writeJSON(w, http.StatusOK, result)
+	writeJSON(w, http.StatusOK, msg)
+}
+
+func decodeJSON(w http.ResponseWriter, r *http.Request, dst interface{}) error {
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBodyBytes))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(dst); err != nil {
+		return fmt.Errorf("invalid request body: %w", err)
+	}
+	return nil
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, service.ErrValidation):
+		status = http.StatusBadRequest
+	case errors.Is(err, service.ErrNotFound), errors.Is(err, service.ErrScheduleNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, service.ErrIdempotencyConflict), errors.Is(err, service.ErrConflict):
+		status = http.StatusConflict
+	case errors.Is(err, service.ErrVersionMismatch):
+		status = http.StatusPreconditionFailed
+	}
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+func etag(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
+}
+
+// parseIfMatch reads the message version from a strong or weak ETag in the
+// If-Match header.
+func parseIfMatch(r *http.Request) (int, error) {
+	raw := strings.TrimPrefix(strings.TrimSpace(r.Header.Get("If-Match")), "W/")
+	version, err := strconv.Atoi(strings.Trim(raw, `"`))
+	if err != nil || version <= 0 {
+		return 0, errors.New("invalid If-Match header: expected the message ETag")
+	}
+	return version, nil
 }
 
 func parseIntDefault(value string, def int) int {
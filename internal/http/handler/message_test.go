@@ -0,0 +1,99 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"automessaging/internal/model"
+	"automessaging/internal/repository"
+	"automessaging/internal/service"
+)
+
+func TestCreateBatchReplaysIdempotencyKey(t *testing.T) {
+	repo := newBatchRepo()
+	h := newTestMessageHandler(repo)
+	body := `{"messages":[{"to":"+905551112233","content":"hello"},{"to":"+905551112234","content":"hi"}]}`
+
+	first := postBatch(h, body, "batch-1")
+	if first.Code != http.StatusCreated {
+		t.Fatalf("first request: status %d, body %s", first.Code, first.Body)
+	}
+	replay := postBatch(h, body, "batch-1")
+	if replay.Code != http.StatusOK {
+		t.Fatalf("replay: status %d, body %s", replay.Code, replay.Body)
+	}
+
+	if repo.inserted != 2 {
+		t.Fatalf("inserted %d messages, want 2", repo.inserted)
+	}
+	var original, replayed service.BatchCreateResult
+	decode(t, first, &original)
+	decode(t, replay, &replayed)
+	for i := range original.Items {
+		if *original.Items[i].ID != *replayed.Items[i].ID {
+			t.Fatalf("item %d: replayed id %s, want %s", i, *replayed.Items[i].ID, *original.Items[i].ID)
+		}
+	}
+}
+
+func TestCreateBatchRejectsReusedKeyWithDifferentBody(t *testing.T) {
+	repo := newBatchRepo()
+	h := newTestMessageHandler(repo)
+
+	if rec := postBatch(h, `{"messages":[{"to":"+905551112233","content":"hello"}]}`, "batch-1"); rec.Code != http.StatusCreated {
+		t.Fatalf("first request: status %d, body %s", rec.Code, rec.Body)
+	}
+	rec := postBatch(h, `{"messages":[{"to":"+905551112233","content":"goodbye"}]}`, "batch-1")
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("status %d, want 409; body %s", rec.Code, rec.Body)
+	}
+	if repo.inserted != 1 {
+		t.Fatalf("inserted %d messages, want 1", repo.inserted)
+	}
+}
+
+func newTestMessageHandler(repo repository.MessageRepository) *MessageHandler {
+	svc := service.NewMessageService(service.Dependencies{Repo: repo}, service.MessageServiceOptions{Logger: log.New(io.Discard, "", 0)})
+	return NewMessageHandler(svc)
+}
+
+func postBatch(h *MessageHandler, body, key string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/messages/batch", strings.NewReader(body))
+	req.Header.Set("Idempotency-Key", key)
+	rec := httptest.NewRecorder()
+	h.CreateBatch(rec, req)
+	return rec
+}
+
+func decode(t *testing.T, rec *httptest.ResponseRecorder, dst interface{}) {
+	t.Helper()
+	if err := json.NewDecoder(rec.Body).Decode(dst); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+}
+
+// batchRepo keeps idempotent batches in memory.
+type batchRepo struct {
+	repository.MessageRepository
+	batches  map[string]repository.IdempotentBatch
+	inserted int
+}
+
+func newBatchRepo() *batchRepo {
+	return &batchRepo{batches: make(map[string]repository.IdempotentBatch)}
+}
+
+func (r *batchRepo) CreateBatchIdempotent(_ context.Context, msgs []model.Message, batch repository.IdempotentBatch) (repository.IdempotentBatch, bool, error) {
+	if stored, ok := r.batches[batch.Key]; ok {
+		return stored, false, nil
+	}
+	r.batches[batch.Key] = batch
+	r.inserted += len(msgs)
+	return batch, true, nil
+}
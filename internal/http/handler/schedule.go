@@ -0,0 +1,96 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+
+	"automessaging/internal/model"
+	"automessaging/internal/service"
+)
+
+// ScheduleService abstracts recurring schedule operations for handlers.
+type ScheduleService interface {
+	CreateSchedule(ctx context.Context, input service.CreateScheduleInput) (model.RecurringSchedule, error)
+	GetSchedule(ctx context.Context, id uuid.UUID) (model.RecurringSchedule, error)
+	ListSchedules(ctx context.Context, page, limit int) (service.SchedulesResult, error)
+	DeactivateSchedule(ctx context.Context, id uuid.UUID) (model.RecurringSchedule, error)
+}
+
+// ScheduleHandler provides HTTP endpoints for recurring schedules.
+type ScheduleHandler struct {
+	svc ScheduleService
+}
+
+// NewScheduleHandler builds a ScheduleHandler.
+func NewScheduleHandler(svc ScheduleService) *ScheduleHandler {
+	return &ScheduleHandler{svc: svc}
+}
+
+// Create handles POST /schedules.
+func (h *ScheduleHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var input service.CreateScheduleInput
+	if err := decodeJSON(w, r, &input); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+
+	schedule, err := h.svc.CreateSchedule(r.Context(), input)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, schedule)
+}
+
+// List handles GET /schedules.
+func (h *ScheduleHandler) List(w http.ResponseWriter, r *http.Request) {
+	page := parseIntDefault(r.URL.Query().Get("page"), 1)
+	limit := parseIntDefault(r.URL.Query().Get("limit"), 20)
+
+	result, err := h.svc.ListSchedules(r.Context(), page, limit)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, result)
+}
+
+// Get handles GET /schedules/{id}.
+func (h *ScheduleHandler) Get(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid schedule id"})
+		return
+	}
+
+	schedule, err := h.svc.GetSchedule(r.Context(), id)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, schedule)
+}
+
+// Deactivate handles DELETE /schedules/{id}. The schedule is kept but creates
+// no further messages.
+func (h *ScheduleHandler) Deactivate(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid schedule id"})
+		return
+	}
+
+	schedule, err := h.svc.DeactivateSchedule(r.Context(), id)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, schedule)
+}
@@ -1,6 +1,7 @@
 package httpserver
 
 import (
+	"expvar"
 	"net/http"
 
 	"github.com/go-chi/chi/v5"
@@ -10,7 +11,7 @@ import (
 )
 
 // NewRouter wires HTTP routes.
-func NewRouter(control *handler.ControlHandler, message *handler.MessageHandler) http.Handler {
+func NewRouter(control *handler.ControlHandler, message *handler.MessageHandler, schedule *handler.ScheduleHandler, callback *handler.CallbackHandler) http.Handler {
 	r := chi.NewRouter()
 	r.Use(middleware.RequestID)
 	r.Use(middleware.RealIP)
@@ -26,16 +27,41 @@ func NewRouter(control *handler.ControlHandler, message *handler.MessageHandler)
 	api.Route("/control", func(r chi.Router) {
 		r.Post("/start", control.Start)
 		r.Post("/stop", control.Stop)
+		r.Get("/status", control.Status)
 	})
 
 	api.Route("/messages", func(r chi.Router) {
+		r.Post("/", message.Create)
+		r.Post("/batch", message.CreateBatch)
 		r.Get("/sent", message.ListSent)
+		r.Get("/pending", message.ListPending)
+		r.Get("/failed", message.ListFailed)
+		r.Get("/dead", message.ListDeadLetters)
+		r.Delete("/dead", message.PurgeDeadLetters)
+		r.Post("/dead/requeue", message.RequeueDeadLetters)
+		r.Get("/by-remote-id/{remoteID}", message.GetByRemoteID)
+		r.Get("/{id}", message.Get)
+		r.Patch("/{id}", message.Edit)
+		r.Delete("/{id}", message.Cancel)
+		r.Post("/{id}/requeue", message.Requeue)
+	})
+
+	api.Route("/schedules", func(r chi.Router) {
+		r.Post("/", schedule.Create)
+		r.Get("/", schedule.List)
+		r.Get("/{id}", schedule.Get)
+		r.Delete("/{id}", schedule.Deactivate)
+	})
+
+	api.Route("/callbacks", func(r chi.Router) {
+		r.Post("/delivery", callback.Delivery)
 	})
 
 	fileServer := http.StripPrefix("/api/v1/docs/", http.FileServer(http.Dir("./api")))
 	api.Handle("/docs/*", fileServer)
 
 	r.Mount("/api/v1", api)
+	r.Handle("/debug/vars", expvar.Handler())
 
 	return r
 }
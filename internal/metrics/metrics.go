@@ -0,0 +1,15 @@
+// Package metrics publishes process-wide counters through expvar. They are
+// served as JSON on /debug/vars alongside the runtime's memstats.
+package metrics
+
+import "expvar"
+
+var (
+	// MessagesSent counts messages a provider accepted.
+	MessagesSent = expvar.NewInt("messages_sent_total")
+	// MessagesFailed counts messages moved to failed.
+	MessagesFailed = expvar.NewInt("messages_failed_total")
+	// MessagesExpired counts messages that passed their expires_at before
+	// they could be sent.
+	MessagesExpired = expvar.NewInt("messages_expired_total")
+)
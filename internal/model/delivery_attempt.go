@@ -0,0 +1,17 @@
+package model
+
+// AttemptState describes a journaled call to a delivery provider.
+type AttemptState string
+
+const (
+	// AttemptDispatching marks a call that was started but whose outcome has
+	// not been recorded yet.
+	AttemptDispatching AttemptState = "dispatching"
+	// AttemptAccepted marks a call the provider accepted.
+	AttemptAccepted AttemptState = "accepted"
+	// AttemptFailed marks a call that failed with a known outcome.
+	AttemptFailed AttemptState = "failed"
+	// AttemptUnknown marks a call that was interrupted and never recorded; the
+	// provider may or may not have accepted it.
+	AttemptUnknown AttemptState = "unknown"
+)
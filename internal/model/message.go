@@ -6,12 +6,69 @@ import (
 	"github.com/google/uuid"
 )
 
+// MessageStatus describes where a message is in its delivery lifecycle.
+type MessageStatus string
+
+const (
+	// StatusQueued marks a message waiting to be claimed by a scheduler.
+	StatusQueued MessageStatus = "queued"
+	// StatusProcessing marks a message leased by a scheduler instance.
+	StatusProcessing MessageStatus = "processing"
+	// StatusSent marks a message accepted by the webhook.
+	StatusSent MessageStatus = "sent"
+	// StatusDelivered marks a sent message the carrier confirmed on the handset.
+	StatusDelivered MessageStatus = "delivered"
+	// StatusUndelivered marks a sent message the carrier reported as not delivered.
+	StatusUndelivered MessageStatus = "undelivered"
+	// StatusFailed marks a message that will not be retried automatically.
+	StatusFailed MessageStatus = "failed"
+	// StatusCancelled marks a message withdrawn before it was sent.
+	StatusCancelled MessageStatus = "cancelled"
+	// StatusExpired marks a message that became stale before it could be sent.
+	StatusExpired MessageStatus = "expired"
+)
+
+// Message priorities: higher values are claimed first.
+const (
+	MinPriority     = 0
+	MaxPriority     = 9
+	DefaultPriority = 5
+)
+
 // Message represents the data stored in PostgreSQL about messages to be sent.
+// Quiet hours are evaluated in RecipientTimezone, or the configured default
+// when it is empty; Transactional messages are sent during quiet hours.
 type Message struct {
-	ID        uuid.UUID  `db:"id" json:"id"`
-	To        string     `db:"to" json:"to"`
-	Content   string     `db:"content" json:"content"`
-	Sent      bool       `db:"sent" json:"sent"`
-	SentAt    *time.Time `db:"sent_at" json:"sent_at,omitempty"`
-	CreatedAt time.Time  `db:"created_at" json:"created_at"`
+	ID                uuid.UUID     `db:"id" json:"id"`
+	To                string        `db:"to" json:"to"`
+	Content           string        `db:"content" json:"content"`
+	Status            MessageStatus `db:"status" json:"status"`
+	Priority          int           `db:"priority" json:"priority"`
+	Attempts          int           `db:"attempts" json:"attempts"`
+	LastError         string        `db:"last_error" json:"last_error,omitempty"`
+	LastErrorClass    string        `db:"last_error_class" json:"last_error_class,omitempty"`
+	LastAttemptAt     *time.Time    `db:"last_attempt_at" json:"last_attempt_at,omitempty"`
+	NextAttemptAt     *time.Time    `db:"next_attempt_at" json:"next_attempt_at,omitempty"`
+	RemoteMessageID   string        `db:"remote_message_id" json:"remote_message_id,omitempty"`
+	Route             string        `db:"route" json:"route,omitempty"`
+	Provider          string        `db:"provider" json:"provider,omitempty"`
+	SentAt            *time.Time    `db:"sent_at" json:"sent_at,omitempty"`
+	ReceiptAt         *time.Time    `db:"receipt_at" json:"receipt_at,omitempty"`
+	CarrierError      string        `db:"carrier_error_code" json:"carrier_error_code,omitempty"`
+	SendAt            *time.Time    `db:"send_at" json:"send_at,omitempty"`
+	ExpiresAt         *time.Time    `db:"expires_at" json:"expires_at,omitempty"`
+	RecipientTimezone string        `db:"recipient_timezone" json:"recipient_timezone,omitempty"`
+	Transactional     bool          `db:"transactional" json:"transactional"`
+	IdempotencyKey    string        `db:"idempotency_key" json:"idempotency_key,omitempty"`
+	ScheduleID        *uuid.UUID    `db:"schedule_id" json:"schedule_id,omitempty"`
+	OccurrenceAt      *time.Time    `db:"occurrence_at" json:"occurrence_at,omitempty"`
+	Version           int           `db:"version" json:"version"`
+	CreatedAt         time.Time     `db:"created_at" json:"created_at"`
+
+	// IdempotencyFingerprint is a digest of the submitted payload used to
+	// detect a reused idempotency key with a different body.
+	IdempotencyFingerprint string `db:"idempotency_fingerprint" json:"-"`
+	// RecipientTimezoneInferred is set when RecipientTimezone was derived
+	// from the number rather than given, so it follows the number on edits.
+	RecipientTimezoneInferred bool `db:"recipient_timezone_inferred" json:"-"`
 }
@@ -0,0 +1,31 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// RecurringSchedule enqueues a message for every occurrence of a cron
+// expression evaluated in Timezone, between StartsAt and EndsAt.
+// NextOccurrenceAt is the next occurrence not yet turned into a message; it is
+// nil once the schedule has no further occurrences. RecipientTimezone is
+// copied to every message: Timezone when the caller gave one, otherwise the
+// zone inferred from To, or empty for the quiet-hours default.
+type RecurringSchedule struct {
+	ID               uuid.UUID  `db:"id" json:"id"`
+	To               string     `db:"to" json:"to"`
+	Content          string     `db:"content" json:"content"`
+	CronExpression   string     `db:"cron_expression" json:"cron_expression"`
+	Timezone         string     `db:"timezone" json:"timezone"`
+	Priority         int        `db:"priority" json:"priority"`
+	TTLSeconds       *int       `db:"ttl_seconds" json:"ttl_seconds,omitempty"`
+	StartsAt         time.Time  `db:"starts_at" json:"starts_at"`
+	EndsAt           *time.Time `db:"ends_at" json:"ends_at,omitempty"`
+	NextOccurrenceAt *time.Time `db:"next_occurrence_at" json:"next_occurrence_at,omitempty"`
+	Active           bool       `db:"active" json:"active"`
+	CreatedAt        time.Time  `db:"created_at" json:"created_at"`
+
+	RecipientTimezone         string `db:"recipient_timezone" json:"recipient_timezone,omitempty"`
+	RecipientTimezoneInferred bool   `db:"recipient_timezone_inferred" json:"-"`
+}
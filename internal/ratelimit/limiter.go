@@ -0,0 +1,189 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// gcraScript applies the generic cell rate algorithm to every key atomically:
+// the call is admitted only if all keys have capacity, and only then are their
+// theoretical arrival times advanced. ARGV holds an (emission interval in ms,
+// burst) pair per key. It returns {1, 0} when admitted or {0, wait_ms}.
+var gcraScript = redis.NewScript(`
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000 + tonumber(t[2]) / 1000
+local wait = 0
+local tats = {}
+for i, key in ipairs(KEYS) do
+  local emission = tonumber(ARGV[i * 2 - 1])
+  local burst = tonumber(ARGV[i * 2])
+  local tat = tonumber(redis.call('GET', key))
+  if not tat or tat < now then
+    tat = now
+  end
+  local new_tat = tat + emission
+  local allow_at = new_tat - emission * burst
+  if allow_at - now > wait then
+    wait = allow_at - now
+  end
+  tats[i] = new_tat
+end
+if wait > 0 then
+  return {0, math.ceil(wait)}
+end
+for i, key in ipairs(KEYS) do
+  redis.call('SET', key, string.format('%.3f', tats[i]), 'PX', math.ceil(tats[i] - now) + 1000)
+end
+return {1, 0}
+`)
+
+// pauseScript sets a pause marker unless a longer pause is already in place.
+var pauseScript = redis.NewScript(`
+local ttl = redis.call('PTTL', KEYS[1])
+if ttl < tonumber(ARGV[1]) then
+  redis.call('SET', KEYS[1], '1', 'PX', ARGV[1])
+end
+return 1
+`)
+
+// Limit describes a sustained rate with an allowance for short bursts.
+type Limit struct {
+	PerSecond float64
+	Burst     int
+}
+
+// Enabled reports whether the limit restricts anything.
+func (l Limit) Enabled() bool {
+	return l.PerSecond > 0
+}
+
+func (l Limit) emissionMillis() float64 {
+	return 1000 / l.PerSecond
+}
+
+func (l Limit) burst() int {
+	if l.Burst < 1 {
+		return 1
+	}
+	return l.Burst
+}
+
+// Config configures a Limiter. Destination applies to each destination
+// separately while Global is shared by all of them. Destinations overrides
+// Destination for the destinations it names.
+type Config struct {
+	Global       Limit
+	Destination  Limit
+	Destinations map[string]Limit
+	KeyPrefix    string
+}
+
+func (c Config) destinationLimit(destination string) Limit {
+	if limit, ok := c.Destinations[destination]; ok {
+		return limit
+	}
+	return c.Destination
+}
+
+// Limiter throttles outbound calls using state shared through Redis so the
+// limits hold across every replica.
+type Limiter struct {
+	client redis.Cmdable
+	cfg    Config
+}
+
+// New builds a Limiter.
+func New(client redis.Cmdable, cfg Config) *Limiter {
+	if cfg.KeyPrefix == "" {
+		cfg.KeyPrefix = "ratelimit"
+	}
+	return &Limiter{client: client, cfg: cfg}
+}
+
+// Allow reserves capacity for one call to destination. It returns zero when
+// the call may proceed, or how long to wait before asking again.
+func (l *Limiter) Allow(ctx context.Context, destination string) (time.Duration, error) {
+	var keys []string
+	var args []interface{}
+	// The hash tag keeps every key in one cluster slot so the script stays atomic.
+	if l.cfg.Global.Enabled() {
+		keys = append(keys, fmt.Sprintf("{%s}:global", l.cfg.KeyPrefix))
+		args = append(args, l.cfg.Global.emissionMillis(), l.cfg.Global.burst())
+	}
+	if limit := l.cfg.destinationLimit(destination); limit.Enabled() {
+		keys = append(keys, fmt.Sprintf("{%s}:destination:%s", l.cfg.KeyPrefix, destination))
+		args = append(args, limit.emissionMillis(), limit.burst())
+	}
+	if len(keys) == 0 {
+		return 0, nil
+	}
+
+	res, err := gcraScript.Run(ctx, l.client, keys, args...).Int64Slice()
+	if err != nil {
+		return 0, err
+	}
+	if len(res) != 2 {
+		return 0, errors.New("unexpected rate limiter response")
+	}
+	if res[0] == 1 {
+		return 0, nil
+	}
+	return time.Duration(res[1]) * time.Millisecond, nil
+}
+
+// Pause stops admitting calls to destination for d on every replica, e.g.
+// after the provider responded with Retry-After. An existing longer pause is
+// kept.
+func (l *Limiter) Pause(ctx context.Context, destination string, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+	return pauseScript.Run(ctx, l.client, []string{l.pauseKey(destination)}, d.Milliseconds()).Err()
+}
+
+// PausedFor reports how long destination remains paused; zero means it is not.
+func (l *Limiter) PausedFor(ctx context.Context, destination string) (time.Duration, error) {
+	ttl, err := l.client.PTTL(ctx, l.pauseKey(destination)).Result()
+	if err != nil {
+		return 0, err
+	}
+	if ttl < 0 {
+		return 0, nil
+	}
+	return ttl, nil
+}
+
+func (l *Limiter) pauseKey(destination string) string {
+	return fmt.Sprintf("%s:pause:%s", l.cfg.KeyPrefix, destination)
+}
+
+// Wait blocks until destination is no longer paused and a call to it is
+// admitted, or ctx is done.
+func (l *Limiter) Wait(ctx context.Context, destination string) error {
+	for {
+		wait, err := l.PausedFor(ctx, destination)
+		if err != nil {
+			return err
+		}
+		if wait == 0 {
+			if wait, err = l.Allow(ctx, destination); err != nil {
+				return err
+			}
+		}
+		if wait == 0 {
+			return nil
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func newTestLimiter(t *testing.T, cfg Config) *Limiter {
+	t.Helper()
+	srv := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: srv.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return New(client, cfg)
+}
+
+func TestAllowAdmitsBurstThenAsksToWait(t *testing.T) {
+	limiter := newTestLimiter(t, Config{Destination: Limit{PerSecond: 1, Burst: 3}})
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		wait, err := limiter.Allow(ctx, "primary")
+		if err != nil || wait != 0 {
+			t.Fatalf("call %d: wait %v, err %v; want admitted within the burst", i+1, wait, err)
+		}
+	}
+
+	wait, err := limiter.Allow(ctx, "primary")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if wait <= 0 || wait > time.Second {
+		t.Fatalf("wait = %v, want up to one emission interval", wait)
+	}
+}
+
+func TestDestinationLimitsAreIndependent(t *testing.T) {
+	limiter := newTestLimiter(t, Config{Destination: Limit{PerSecond: 1, Burst: 1}})
+	ctx := context.Background()
+
+	if wait, _ := limiter.Allow(ctx, "primary"); wait != 0 {
+		t.Fatalf("first call to primary: wait %v", wait)
+	}
+	if wait, _ := limiter.Allow(ctx, "primary"); wait == 0 {
+		t.Fatal("expected primary to be limited")
+	}
+	if wait, _ := limiter.Allow(ctx, "backup"); wait != 0 {
+		t.Fatalf("backup should have its own budget, got wait %v", wait)
+	}
+}
+
+func TestDestinationOverrideReplacesDefaultLimit(t *testing.T) {
+	limiter := newTestLimiter(t, Config{
+		Destination:  Limit{PerSecond: 1, Burst: 1},
+		Destinations: map[string]Limit{"bulk": {PerSecond: 1, Burst: 3}},
+	})
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		if wait, _ := limiter.Allow(ctx, "bulk"); wait != 0 {
+			t.Fatalf("call %d to bulk: wait %v; want admitted within its own burst", i+1, wait)
+		}
+	}
+	if wait, _ := limiter.Allow(ctx, "bulk"); wait == 0 {
+		t.Fatal("expected bulk to be limited after its burst")
+	}
+	if wait, _ := limiter.Allow(ctx, "primary"); wait != 0 {
+		t.Fatalf("first call to primary: wait %v", wait)
+	}
+	if wait, _ := limiter.Allow(ctx, "primary"); wait == 0 {
+		t.Fatal("expected primary to keep the default burst of 1")
+	}
+}
+
+func TestGlobalLimitIsSharedAndRejectedCallsConsumeNothing(t *testing.T) {
+	limiter := newTestLimiter(t, Config{
+		Global:      Limit{PerSecond: 1, Burst: 2},
+		Destination: Limit{PerSecond: 1, Burst: 1},
+	})
+	ctx := context.Background()
+
+	if wait, _ := limiter.Allow(ctx, "primary"); wait != 0 {
+		t.Fatalf("primary: wait %v", wait)
+	}
+	// Rejected by primary's own limit; the global budget must be left alone.
+	if wait, _ := limiter.Allow(ctx, "primary"); wait == 0 {
+		t.Fatal("expected primary to be limited")
+	}
+	if wait, _ := limiter.Allow(ctx, "backup"); wait != 0 {
+		t.Fatalf("backup: wait %v; the rejected call should not have used global capacity", wait)
+	}
+	if wait, _ := limiter.Allow(ctx, "tertiary"); wait == 0 {
+		t.Fatal("expected the global burst to be exhausted")
+	}
+}
+
+func TestPauseKeepsLongerPause(t *testing.T) {
+	limiter := newTestLimiter(t, Config{})
+	ctx := context.Background()
+
+	if err := limiter.Pause(ctx, "primary", time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if err := limiter.Pause(ctx, "primary", time.Second); err != nil {
+		t.Fatal(err)
+	}
+
+	paused, err := limiter.PausedFor(ctx, "primary")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if paused <= time.Second {
+		t.Fatalf("paused for %v, want the longer pause to win", paused)
+	}
+	if paused, _ := limiter.PausedFor(ctx, "backup"); paused != 0 {
+		t.Fatalf("backup paused for %v, want 0", paused)
+	}
+}
+
+func TestWaitReturnsWhenContextEnds(t *testing.T) {
+	limiter := newTestLimiter(t, Config{})
+	if err := limiter.Pause(context.Background(), "primary", time.Minute); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := limiter.Wait(ctx, "primary"); err != context.DeadlineExceeded {
+		t.Fatalf("err = %v, want deadline exceeded", err)
+	}
+}
@@ -0,0 +1,63 @@
+// Package recurrence evaluates the cron expressions of recurring schedules.
+package recurrence
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/robfig/cron/v3"
+
+	"automessaging/internal/model"
+	"automessaging/internal/timezone"
+)
+
+var cronParser = cron.NewParser(cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
+
+// Recurrence is a parsed cron expression bound to the time zone it is
+// evaluated in, so "0 9 * * MON" means 09:00 local time across DST changes.
+type Recurrence struct {
+	schedule cron.Schedule
+	location *time.Location
+}
+
+// Parse parses a standard five-field cron expression or a descriptor such as
+// @weekly, evaluated in the IANA time zone zone.
+func Parse(expression, zone string) (Recurrence, error) {
+	if strings.HasPrefix(expression, "TZ=") || strings.HasPrefix(expression, "CRON_TZ=") {
+		return Recurrence{}, fmt.Errorf("time zone must be given separately, not in the cron expression")
+	}
+	location, err := timezone.Load(zone)
+	if err != nil {
+		return Recurrence{}, fmt.Errorf("unknown time zone %q", zone)
+	}
+	schedule, err := cronParser.Parse(expression)
+	if err != nil {
+		return Recurrence{}, fmt.Errorf("invalid cron expression: %w", err)
+	}
+	return Recurrence{schedule: schedule, location: location}, nil
+}
+
+// Next returns the first occurrence strictly after after, in UTC. It returns
+// the zero time when the expression can never match.
+func (r Recurrence) Next(after time.Time) time.Time {
+	next := r.schedule.Next(after.In(r.location))
+	if next.IsZero() {
+		return next
+	}
+	return next.UTC()
+}
+
+// NextOccurrence returns the occurrence of schedule after after, or false
+// once the schedule's end has passed or its expression no longer parses.
+func NextOccurrence(schedule model.RecurringSchedule, after time.Time) (time.Time, bool) {
+	recurrence, err := Parse(schedule.CronExpression, schedule.Timezone)
+	if err != nil {
+		return time.Time{}, false
+	}
+	next := recurrence.Next(after)
+	if next.IsZero() || (schedule.EndsAt != nil && next.After(*schedule.EndsAt)) {
+		return time.Time{}, false
+	}
+	return next, true
+}
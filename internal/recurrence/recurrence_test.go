@@ -0,0 +1,49 @@
+package recurrence
+
+import (
+	"testing"
+	"time"
+
+	"automessaging/internal/model"
+)
+
+func TestRecurrenceKeepsLocalTimeAcrossDST(t *testing.T) {
+	recurrence, err := Parse("0 9 * * MON", "Europe/Berlin")
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+
+	// Berlin switches from CET (UTC+1) to CEST (UTC+2) on 2025-03-30.
+	before := recurrence.Next(time.Date(2025, 3, 22, 0, 0, 0, 0, time.UTC))
+	after := recurrence.Next(before)
+
+	if want := time.Date(2025, 3, 24, 8, 0, 0, 0, time.UTC); !before.Equal(want) {
+		t.Fatalf("first occurrence = %v, want %v", before, want)
+	}
+	if want := time.Date(2025, 3, 31, 7, 0, 0, 0, time.UTC); !after.Equal(want) {
+		t.Fatalf("second occurrence = %v, want %v", after, want)
+	}
+}
+
+func TestParseRejectsInvalidInput(t *testing.T) {
+	for _, tc := range []struct{ expression, timezone string }{
+		{"0 9 * *", "UTC"},
+		{"0 9 * * MON", "Mars/Olympus"},
+		{"0 9 * * MON", "Local"},
+		{"0 9 * * MON", ""},
+		{"CRON_TZ=Asia/Tokyo 0 9 * * *", "UTC"},
+	} {
+		if _, err := Parse(tc.expression, tc.timezone); err == nil {
+			t.Errorf("Parse(%q, %q) succeeded, want an error", tc.expression, tc.timezone)
+		}
+	}
+}
+
+func TestNextOccurrenceStopsAtEnd(t *testing.T) {
+	endsAt := time.Date(2025, 1, 31, 0, 0, 0, 0, time.UTC)
+	schedule := model.RecurringSchedule{CronExpression: "@monthly", Timezone: "UTC", EndsAt: &endsAt}
+
+	if _, ok := NextOccurrence(schedule, time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)); ok {
+		t.Fatal("expected no occurrence after ends_at")
+	}
+}
@@ -2,6 +2,7 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"time"
 
 	"github.com/google/uuid"
@@ -9,9 +10,195 @@ import (
 	"automessaging/internal/model"
 )
 
+// ErrLeaseLost is returned when an outcome is recorded for a message the
+// caller no longer holds the lease on, e.g. because it expired and another
+// replica claimed the message since.
+var ErrLeaseLost = errors.New("message lease lost")
+
+// DeliveryFailure describes a failed delivery attempt and the status the
+// message should move to afterwards. NextAttemptAt is only meaningful when the
+// message is returned to the queue. Attempts that the provider throttled are
+// recorded without counting towards the attempt total.
+type DeliveryFailure struct {
+	Status        model.MessageStatus
+	AttemptedAt   time.Time
+	NextAttemptAt *time.Time
+	Error         string
+	ErrorClass    string
+	CountAttempt  bool
+	Route         string
+	Provider      string
+}
+
+// DeliverySuccess describes an attempt the provider accepted. Route is the
+// recipient prefix that selected the destinations, empty when none matched.
+// AttemptID, when set, is the journal entry closed in the same transaction.
+type DeliverySuccess struct {
+	SentAt    time.Time
+	RemoteID  string
+	Route     string
+	Provider  string
+	AttemptID uuid.UUID
+}
+
+// AttemptOutcome closes a journaled provider call.
+type AttemptOutcome struct {
+	State    model.AttemptState
+	RemoteID string
+	Error    string
+}
+
+// ReapResult reports what happened to messages whose lease expired. Messages
+// whose journal shows an accepted call are marked sent, those with an
+// unfinished call are failed because they may already have been delivered,
+// and the rest are requeued.
+type ReapResult struct {
+	Requeued   int64
+	Reconciled int64
+	Unknown    int64
+}
+
+// DeliveryReceipt is a carrier report on a sent message. Status is either
+// delivered or undelivered; At is when the carrier reached that state.
+type DeliveryReceipt struct {
+	Status    model.MessageStatus
+	At        time.Time
+	ErrorCode string
+}
+
+// DeadLetterFilter narrows dead-letter operations. Zero values match every
+// dead-lettered message.
+type DeadLetterFilter struct {
+	IDs           []uuid.UUID
+	To            string
+	ErrorContains string
+	FailedAfter   *time.Time
+	FailedBefore  *time.Time
+}
+
+// IsEmpty reports whether the filter matches every dead-lettered message.
+func (f DeadLetterFilter) IsEmpty() bool {
+	return len(f.IDs) == 0 && f.To == "" && f.ErrorContains == "" && f.FailedAfter == nil && f.FailedBefore == nil
+}
+
+// QuietHours is a daily window, in each recipient's local time, during which
+// non-transactional messages are not claimed. Start and End are offsets from
+// local midnight and the window wraps past midnight when Start is after End.
+// Messages without a recipient time zone use DefaultTimezone.
+type QuietHours struct {
+	Start           time.Duration
+	End             time.Duration
+	DefaultTimezone string
+}
+
+// ClaimRequest describes a batch to lease. OldestFirst of the Limit slots are
+// filled by due time regardless of priority. When QuietHours is set, due
+// messages inside the window are deferred to when it closes instead.
+type ClaimRequest struct {
+	Owner       string
+	Lease       time.Duration
+	Limit       int
+	OldestFirst int
+	QuietHours  *QuietHours
+}
+
+// IdempotentBatch is the stored outcome of a batch submitted with an
+// idempotency key: the key, a digest of the request body and the response
+// returned for it.
+type IdempotentBatch struct {
+	Key         string
+	Fingerprint string
+	Result      []byte
+}
+
+// MessageEdit changes a queued message. Nil fields are left as they are.
+// RecipientTimezone is the zone inferred for a new To ("" when none is);
+// it replaces the stored zone only when that was inferred too, so a zone
+// the caller gave survives a change of number.
+type MessageEdit struct {
+	To                *string
+	Content           *string
+	SendAt            *time.Time
+	Priority          *int
+	RecipientTimezone *string
+}
+
+// MessageView names a message listing. Each view covers a fixed set of
+// statuses and is ordered and paged by one timestamp.
+type MessageView string
+
+const (
+	// ViewSent lists sent, delivered and undelivered messages by sent_at,
+	// newest first.
+	ViewSent MessageView = "sent"
+	// ViewPending lists queued and processing messages by created_at,
+	// oldest first.
+	ViewPending MessageView = "pending"
+	// ViewFailed lists failed messages by last_attempt_at, newest first.
+	ViewFailed MessageView = "failed"
+)
+
+// Statuses returns the statuses the view covers.
+func (v MessageView) Statuses() []model.MessageStatus {
+	switch v {
+	case ViewSent:
+		return []model.MessageStatus{model.StatusSent, model.StatusDelivered, model.StatusUndelivered}
+	case ViewPending:
+		return []model.MessageStatus{model.StatusQueued, model.StatusProcessing}
+	case ViewFailed:
+		return []model.MessageStatus{model.StatusFailed}
+	}
+	return nil
+}
+
+// Cursor is a keyset position within a view: the ordering timestamp and id
+// of the last message already returned.
+type Cursor struct {
+	At time.Time
+	ID uuid.UUID
+}
+
+// MessageQuery selects a page of a view. Statuses narrows the view's
+// statuses and must be a subset of them; empty keeps them all. From and
+// Until bound the view's timestamp. When After is set the page continues
+// from it and Offset is ignored. The total is only counted on request
+// because it scans every matching row.
+type MessageQuery struct {
+	View            MessageView
+	Statuses        []model.MessageStatus
+	To              string
+	ContentContains string
+	From            *time.Time
+	Until           *time.Time
+	After           *Cursor
+	Offset          int
+	Limit           int
+	CountTotal      bool
+}
+
 // MessageRepository defines the database operations required for messages.
 type MessageRepository interface {
-	FetchNextUnsent(ctx context.Context, limit int) ([]model.Message, error)
-	MarkAsSent(ctx context.Context, id uuid.UUID, sentAt time.Time) error
-	ListSent(ctx context.Context, offset, limit int) ([]model.Message, int, error)
+	Create(ctx context.Context, msg *model.Message) error
+	CreateIdempotent(ctx context.Context, msg *model.Message) (bool, error)
+	CreateBatch(ctx context.Context, msgs []model.Message) ([]model.Message, error)
+	CreateBatchIdempotent(ctx context.Context, msgs []model.Message, batch IdempotentBatch) (IdempotentBatch, bool, error)
+	ClaimNextUnsent(ctx context.Context, req ClaimRequest) ([]model.Message, error)
+	ReleaseClaim(ctx context.Context, id uuid.UUID, owner string, retryAt *time.Time) error
+	ExpireStale(ctx context.Context) (int64, error)
+	UpdateQueued(ctx context.Context, id uuid.UUID, version int, edit MessageEdit) (model.Message, error)
+	CancelQueued(ctx context.Context, id uuid.UUID, version int) (model.Message, error)
+	ReapExpiredLeases(ctx context.Context) (ReapResult, error)
+	BeginAttempt(ctx context.Context, messageID uuid.UUID, owner, provider string) (uuid.UUID, error)
+	FinishAttempt(ctx context.Context, attemptID uuid.UUID, outcome AttemptOutcome) error
+	MarkAsSent(ctx context.Context, id uuid.UUID, owner string, sent DeliverySuccess) error
+	RecordFailure(ctx context.Context, id uuid.UUID, owner string, failure DeliveryFailure) error
+	FindByID(ctx context.Context, id uuid.UUID) (model.Message, error)
+	FindByRemoteID(ctx context.Context, remoteID string) (model.Message, error)
+	ListAccepted(ctx context.Context, afterSentAt time.Time, afterID uuid.UUID, limit int) ([]model.Message, error)
+	RecordReceipt(ctx context.Context, id uuid.UUID, receipt DeliveryReceipt) (bool, error)
+	ListMessages(ctx context.Context, query MessageQuery) ([]model.Message, int, error)
+	ListDeadLetters(ctx context.Context, filter DeadLetterFilter, offset, limit int) ([]model.Message, int, error)
+	Requeue(ctx context.Context, id uuid.UUID) error
+	RequeueDeadLetters(ctx context.Context, filter DeadLetterFilter) (int64, error)
+	PurgeDeadLetters(ctx context.Context, filter DeadLetterFilter) (int64, error)
 }
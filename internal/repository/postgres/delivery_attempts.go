@@ -0,0 +1,145 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/google/uuid"
+
+	"automessaging/internal/model"
+	"automessaging/internal/repository"
+)
+
+// execer is satisfied by both *sql.DB and *sql.Tx.
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// BeginAttempt journals a provider call before it is made.
+func (r *MessageRepository) BeginAttempt(ctx context.Context, messageID uuid.UUID, owner, provider string) (uuid.UUID, error) {
+	var id uuid.UUID
+	err := r.db.QueryRowContext(ctx, `
+        INSERT INTO delivery_attempts (message_id, lease_owner, provider)
+        VALUES ($1, $2, $3)
+        RETURNING id`, messageID, owner, provider).Scan(&id)
+	return id, err
+}
+
+// FinishAttempt records the outcome of a journaled provider call.
+func (r *MessageRepository) FinishAttempt(ctx context.Context, attemptID uuid.UUID, outcome repository.AttemptOutcome) error {
+	return finishAttempt(ctx, r.db, attemptID, outcome)
+}
+
+func finishAttempt(ctx context.Context, db execer, attemptID uuid.UUID, outcome repository.AttemptOutcome) error {
+	res, err := db.ExecContext(ctx, `
+        UPDATE delivery_attempts
+        SET state = $2,
+            remote_message_id = NULLIF($3, ''),
+            error = NULLIF($4, ''),
+            finished_at = NOW()
+        WHERE id = $1 AND state = 'dispatching'`, attemptID, outcome.State, outcome.RemoteID, outcome.Error)
+	if err != nil {
+		return err
+	}
+	return requireAffected(res)
+}
+
+// ReapExpiredLeases resolves messages abandoned by crashed or stalled
+// instances using the attempt journal; see repository.ReapResult. Only
+// attempts made under the expired lease count: same owner, started after the
+// claim. Rows claimed before leased_at existed match any attempt of the owner.
+func (r *MessageRepository) ReapExpiredLeases(ctx context.Context) (repository.ReapResult, error) {
+	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return repository.ReapResult{}, err
+	}
+	defer tx.Rollback()
+
+	var result repository.ReapResult
+
+	// The provider accepted the message but the row was never updated.
+	if err := tx.QueryRowContext(ctx, `
+        WITH reconciled AS (
+            UPDATE messages m
+            SET status = 'sent',
+                sent_at = a.finished_at,
+                remote_message_id = a.remote_message_id,
+                provider = a.provider,
+                attempts = m.attempts + 1,
+                last_attempt_at = a.finished_at,
+                next_attempt_at = NULL,
+                last_error = NULL,
+                last_error_class = NULL,
+                lease_owner = NULL,
+                lease_expires_at = NULL
+            FROM delivery_attempts a
+            WHERE a.message_id = m.id
+              AND a.lease_owner = m.lease_owner
+              AND a.started_at >= COALESCE(m.leased_at, '-infinity')
+              AND a.state = 'accepted'
+              AND m.status = 'processing'
+              AND m.lease_expires_at < NOW()
+            RETURNING m.id
+        )
+        SELECT COUNT(*) FROM reconciled`).Scan(&result.Reconciled); err != nil {
+		return repository.ReapResult{}, err
+	}
+
+	// A call was started but never finished: it may have been delivered, so
+	// the message is failed for review instead of being sent again.
+	if err := tx.QueryRowContext(ctx, `
+        WITH expired AS (
+            SELECT m.id, m.lease_owner, COALESCE(m.leased_at, '-infinity') AS leased_at
+            FROM messages m
+            WHERE m.status = 'processing'
+              AND m.lease_expires_at < NOW()
+              AND EXISTS (
+                  SELECT 1 FROM delivery_attempts a
+                  WHERE a.message_id = m.id
+                    AND a.lease_owner = m.lease_owner
+                    AND a.started_at >= COALESCE(m.leased_at, '-infinity')
+                    AND a.state = 'dispatching'
+              )
+            FOR UPDATE OF m
+        ), parked AS (
+            UPDATE messages m
+            SET status = 'failed',
+                attempts = m.attempts + 1,
+                last_attempt_at = NOW(),
+                next_attempt_at = NULL,
+                last_error = 'delivery outcome unknown: instance stopped while calling the provider',
+                last_error_class = 'unknown',
+                lease_owner = NULL,
+                lease_expires_at = NULL
+            FROM expired
+            WHERE m.id = expired.id
+            RETURNING m.id
+        ), abandoned AS (
+            UPDATE delivery_attempts a
+            SET state = $1, finished_at = NOW()
+            FROM expired
+            WHERE a.message_id = expired.id
+              AND a.lease_owner = expired.lease_owner
+              AND a.started_at >= expired.leased_at
+              AND a.state = 'dispatching'
+        )
+        SELECT COUNT(*) FROM parked`, model.AttemptUnknown).Scan(&result.Unknown); err != nil {
+		return repository.ReapResult{}, err
+	}
+
+	res, err := tx.ExecContext(ctx, `
+        UPDATE messages
+        SET status = 'queued', lease_owner = NULL, lease_expires_at = NULL
+        WHERE status = 'processing' AND lease_expires_at < NOW()`)
+	if err != nil {
+		return repository.ReapResult{}, err
+	}
+	if result.Requeued, err = res.RowsAffected(); err != nil {
+		return repository.ReapResult{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return repository.ReapResult{}, err
+	}
+	return result, nil
+}
@@ -0,0 +1,52 @@
+package postgres
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+
+	"automessaging/internal/model"
+	"automessaging/internal/repository"
+)
+
+// UpdateQueued applies edit to a queued message still at version and bumps
+// the version. A recipient zone that was inferred rather than given is
+// replaced by edit.RecipientTimezone. A new send_at clears next_attempt_at,
+// so a pending retry or quiet-hours deferral cannot hold the message past it.
+// It returns
+// sql.ErrNoRows when the message is missing, no longer queued, at another
+// version, or would expire at or before the new send_at.
+func (r *MessageRepository) UpdateQueued(ctx context.Context, id uuid.UUID, version int, edit repository.MessageEdit) (model.Message, error) {
+	var zone string
+	if edit.RecipientTimezone != nil {
+		zone = *edit.RecipientTimezone
+	}
+	return scanMessage(r.db.QueryRowContext(ctx, `
+        UPDATE messages
+        SET "to" = COALESCE($3, "to"),
+            content = COALESCE($4, content),
+            send_at = COALESCE($5, send_at),
+            next_attempt_at = CASE WHEN $5::timestamptz IS NULL THEN next_attempt_at ELSE NULL END,
+            priority = COALESCE($6, priority),
+            recipient_timezone = CASE WHEN $7::boolean AND (recipient_timezone IS NULL OR recipient_timezone_inferred)
+                THEN $8::varchar ELSE recipient_timezone END,
+            recipient_timezone_inferred = CASE WHEN $7::boolean AND (recipient_timezone IS NULL OR recipient_timezone_inferred)
+                THEN $8::varchar IS NOT NULL ELSE recipient_timezone_inferred END,
+            version = version + 1
+        WHERE id = $1 AND status = 'queued' AND version = $2
+          AND ($5::timestamptz IS NULL OR expires_at IS NULL OR expires_at > $5)
+        RETURNING `+messageColumns, id, version, nullString(edit.To), nullString(edit.Content), nullTime(edit.SendAt), nullInt(edit.Priority),
+		edit.RecipientTimezone != nil, nullZone(zone)))
+}
+
+// CancelQueued moves a queued message to cancelled. A zero version matches
+// any version. It returns sql.ErrNoRows when nothing was cancelled.
+func (r *MessageRepository) CancelQueued(ctx context.Context, id uuid.UUID, version int) (model.Message, error) {
+	return scanMessage(r.db.QueryRowContext(ctx, `
+        UPDATE messages
+        SET status = 'cancelled',
+            next_attempt_at = NULL,
+            version = version + 1
+        WHERE id = $1 AND status = 'queued' AND ($2 = 0 OR version = $2)
+        RETURNING `+messageColumns, id, version))
+}
@@ -0,0 +1,112 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"automessaging/internal/model"
+	"automessaging/internal/repository"
+)
+
+// viewOrder is the timestamp a view is ordered and paged by. Every status in
+// the view guarantees the column is set.
+var viewOrder = map[repository.MessageView]struct {
+	column     string
+	descending bool
+}{
+	repository.ViewSent:    {column: "sent_at", descending: true},
+	repository.ViewPending: {column: "created_at"},
+	repository.ViewFailed:  {column: "last_attempt_at", descending: true},
+}
+
+// ListMessages returns a page of query.View ordered by the view's timestamp
+// and id. The total ignores the cursor and offset and is zero unless
+// query.CountTotal is set.
+func (r *MessageRepository) ListMessages(ctx context.Context, query repository.MessageQuery) ([]model.Message, int, error) {
+	order, ok := viewOrder[query.View]
+	if !ok {
+		return nil, 0, fmt.Errorf("unknown message view %q", query.View)
+	}
+
+	where, args := messageQueryWhere(order.column, query)
+
+	var total int
+	if query.CountTotal {
+		if err := r.db.QueryRowContext(ctx, `SELECT COUNT(1) FROM messages WHERE `+where, args...).Scan(&total); err != nil {
+			return nil, 0, err
+		}
+	}
+
+	direction, comparison := "ASC", ">"
+	if order.descending {
+		direction, comparison = "DESC", "<"
+	}
+
+	var offset int
+	if query.After != nil {
+		args = append(args, query.After.At, query.After.ID)
+		where += fmt.Sprintf(" AND (%s, id) %s ($%d, $%d)", order.column, comparison, len(args)-1, len(args))
+	} else {
+		offset = query.Offset
+	}
+	args = append(args, offset, query.Limit)
+
+	rows, err := r.db.QueryContext(ctx, fmt.Sprintf(`
+        SELECT `+messageColumns+`
+        FROM messages
+        WHERE %s
+        ORDER BY %s %s, id %s
+        OFFSET $%d LIMIT $%d`, where, order.column, direction, direction, len(args)-1, len(args)), args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	messages, err := scanMessages(rows)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return messages, total, nil
+}
+
+func messageQueryWhere(column string, query repository.MessageQuery) (string, []interface{}) {
+	// The view's statuses are inlined so the planner can match its partial
+	// index; a narrower selection is added on top.
+	viewStatuses := query.View.Statuses()
+	quoted := make([]string, len(viewStatuses))
+	for i, status := range viewStatuses {
+		quoted[i] = "'" + string(status) + "'"
+	}
+	conditions := []string{"status IN (" + strings.Join(quoted, ", ") + ")"}
+	var args []interface{}
+
+	add := func(condition string, arg interface{}) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+
+	if len(query.Statuses) > 0 {
+		statuses := make([]string, len(query.Statuses))
+		for i, status := range query.Statuses {
+			statuses[i] = string(status)
+		}
+		add("status = ANY($%d::text[])", statuses)
+	}
+
+	if query.To != "" {
+		add(`"to" = $%d`, query.To)
+	}
+	if query.ContentContains != "" {
+		add("strpos(content, $%d) > 0", query.ContentContains)
+	}
+	if query.From != nil {
+		add(column+" >= $%d", *query.From)
+	}
+	if query.Until != nil {
+		add(column+" < $%d", *query.Until)
+	}
+
+	return strings.Join(conditions, " AND "), args
+}
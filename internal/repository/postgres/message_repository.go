@@ -3,6 +3,7 @@ package postgres
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"time"
 
 	"github.com/google/uuid"
@@ -13,6 +14,8 @@ import (
 
 var _ repository.MessageRepository = (*MessageRepository)(nil)
 
+const messageColumns = `id, "to", content, status, priority, attempts, last_error, last_error_class, last_attempt_at, next_attempt_at, remote_message_id, route, provider, sent_at, receipt_at, carrier_error_code, send_at, expires_at, recipient_timezone, recipient_timezone_inferred, transactional, idempotency_key, idempotency_fingerprint, schedule_id, occurrence_at, version, created_at`
+
 // MessageRepository provides PostgreSQL backed message operations.
 type MessageRepository struct {
 	db *sql.DB
@@ -23,90 +26,496 @@ func NewMessageRepository(db *sql.DB) *MessageRepository {
 	return &MessageRepository{db: db}
 }
 
-// FetchNextUnsent retrieves the earliest unsent messages.
-func (r *MessageRepository) FetchNextUnsent(ctx context.Context, limit int) ([]model.Message, error) {
-	rows, err := r.db.QueryContext(ctx, `
-        SELECT id, "to", content, sent, sent_at, created_at
+// Create inserts a new message and populates generated fields on msg.
+func (r *MessageRepository) Create(ctx context.Context, msg *model.Message) error {
+	return r.db.QueryRowContext(ctx, `
+        INSERT INTO messages ("to", content, send_at, expires_at, priority, recipient_timezone, recipient_timezone_inferred, transactional)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+        RETURNING id, status, version, created_at`,
+		msg.To, msg.Content, nullTime(msg.SendAt), nullTime(msg.ExpiresAt), msg.Priority, nullZone(msg.RecipientTimezone), msg.RecipientTimezoneInferred, msg.Transactional,
+	).Scan(&msg.ID, &msg.Status, &msg.Version, &msg.CreatedAt)
+}
+
+// CreateIdempotent inserts msg unless a message with the same idempotency key
+// already exists. In that case msg is overwritten with the stored message and
+// false is returned.
+func (r *MessageRepository) CreateIdempotent(ctx context.Context, msg *model.Message) (bool, error) {
+	err := r.db.QueryRowContext(ctx, `
+        INSERT INTO messages ("to", content, send_at, expires_at, priority, recipient_timezone, recipient_timezone_inferred, transactional, idempotency_key, idempotency_fingerprint)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+        ON CONFLICT (idempotency_key) WHERE idempotency_key IS NOT NULL DO NOTHING
+        RETURNING id, status, version, created_at`,
+		msg.To, msg.Content, nullTime(msg.SendAt), nullTime(msg.ExpiresAt), msg.Priority, nullZone(msg.RecipientTimezone), msg.RecipientTimezoneInferred, msg.Transactional, msg.IdempotencyKey, msg.IdempotencyFingerprint,
+	).Scan(&msg.ID, &msg.Status, &msg.Version, &msg.CreatedAt)
+	if err == nil {
+		return true, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return false, err
+	}
+
+	existing, err := scanMessage(r.db.QueryRowContext(ctx, `
+        SELECT `+messageColumns+`
         FROM messages
-        WHERE sent = false
-        ORDER BY created_at ASC
-        LIMIT $1`, limit)
+        WHERE idempotency_key = $1`, msg.IdempotencyKey))
+	if err != nil {
+		return false, err
+	}
+	*msg = existing
+	return false, nil
+}
+
+// CreateBatch inserts all messages in a single transaction and returns them with generated fields.
+func (r *MessageRepository) CreateBatch(ctx context.Context, msgs []model.Message) ([]model.Message, error) {
+	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{})
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
+	defer tx.Rollback()
 
-	var messages []model.Message
-	for rows.Next() {
-		var msg model.Message
-		var sentAt sql.NullTime
-		if err := rows.Scan(&msg.ID, &msg.To, &msg.Content, &msg.Sent, &sentAt, &msg.CreatedAt); err != nil {
+	created, err := insertMessages(ctx, tx, msgs)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+// CreateBatchIdempotent records batch and inserts msgs in one transaction
+// unless a batch with the same key already exists. In that case nothing is
+// inserted and the stored batch is returned with false. Messages keep the ids
+// they carry so the stored result can reference them.
+func (r *MessageRepository) CreateBatchIdempotent(ctx context.Context, msgs []model.Message, batch repository.IdempotentBatch) (repository.IdempotentBatch, bool, error) {
+	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return repository.IdempotentBatch{}, false, err
+	}
+	defer tx.Rollback()
+
+	// A concurrent request with the same key blocks here until the first
+	// one commits, and then finds its row.
+	res, err := tx.ExecContext(ctx, `
+        INSERT INTO message_batches (idempotency_key, idempotency_fingerprint, result)
+        VALUES ($1, $2, $3)
+        ON CONFLICT (idempotency_key) DO NOTHING`, batch.Key, batch.Fingerprint, batch.Result)
+	if err != nil {
+		return repository.IdempotentBatch{}, false, err
+	}
+	if inserted, err := res.RowsAffected(); err != nil {
+		return repository.IdempotentBatch{}, false, err
+	} else if inserted == 0 {
+		stored := repository.IdempotentBatch{Key: batch.Key}
+		err := tx.QueryRowContext(ctx, `
+            SELECT idempotency_fingerprint, result
+            FROM message_batches
+            WHERE idempotency_key = $1`, batch.Key).Scan(&stored.Fingerprint, &stored.Result)
+		return stored, false, err
+	}
+
+	if _, err := insertMessages(ctx, tx, msgs); err != nil {
+		return repository.IdempotentBatch{}, false, err
+	}
+	if err := tx.Commit(); err != nil {
+		return repository.IdempotentBatch{}, false, err
+	}
+	return batch, true, nil
+}
+
+// insertMessages inserts msgs within tx and returns them with generated
+// fields. Messages without an id get one from the database.
+func insertMessages(ctx context.Context, tx *sql.Tx, msgs []model.Message) ([]model.Message, error) {
+	stmt, err := tx.PrepareContext(ctx, `
+        INSERT INTO messages (id, "to", content, send_at, expires_at, priority, recipient_timezone, recipient_timezone_inferred, transactional)
+        VALUES (COALESCE($1, gen_random_uuid()), $2, $3, $4, $5, $6, $7, $8, $9)
+        RETURNING id, status, version, created_at`)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	created := make([]model.Message, 0, len(msgs))
+	for _, msg := range msgs {
+		id := uuid.NullUUID{UUID: msg.ID, Valid: msg.ID != uuid.Nil}
+		if err := stmt.QueryRowContext(ctx, id, msg.To, msg.Content, nullTime(msg.SendAt), nullTime(msg.ExpiresAt), msg.Priority, nullZone(msg.RecipientTimezone), msg.RecipientTimezoneInferred, msg.Transactional).Scan(&msg.ID, &msg.Status, &msg.Version, &msg.CreatedAt); err != nil {
 			return nil, err
 		}
-		if sentAt.Valid {
-			ts := sentAt.Time
-			msg.SentAt = &ts
+		created = append(created, msg)
+	}
+	return created, nil
+}
+
+// ClaimNextUnsent leases up to req.Limit due messages to req.Owner, highest
+// priority first and then by due time. The last req.OldestFirst slots ignore
+// priority and take the longest-waiting messages, so bulk traffic keeps
+// moving while higher priorities are busy. Rows locked by a concurrent
+// claimer are skipped so replicas never receive the same message. With quiet
+// hours configured, due messages inside the window are first pushed back to
+// when it closes so they are not claimed or rescanned until then.
+func (r *MessageRepository) ClaimNextUnsent(ctx context.Context, req repository.ClaimRequest) ([]model.Message, error) {
+	oldestFirst := min(req.OldestFirst, req.Limit)
+
+	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if req.QuietHours != nil {
+		if err := deferQuietHours(ctx, tx, *req.QuietHours); err != nil {
+			return nil, err
 		}
-		messages = append(messages, msg)
 	}
 
-	return messages, rows.Err()
+	messages, err := claimDue(ctx, tx, req.Owner, req.Lease, req.Limit-oldestFirst, "priority DESC, due_at ASC")
+	if err != nil {
+		return nil, err
+	}
+	// Rows claimed above are no longer queued, so this cannot pick them again.
+	oldest, err := claimDue(ctx, tx, req.Owner, req.Lease, req.Limit-len(messages), "due_at ASC")
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return append(messages, oldest...), nil
+}
+
+// deferQuietHours moves the next attempt of due, non-transactional messages
+// whose recipient is inside the quiet window to the local time it ends.
+// NOW() is fixed for the transaction, so the claims that follow skip them.
+// A stored zone Postgres does not know falls back to the default instead of
+// failing the statement and with it every claim.
+func deferQuietHours(ctx context.Context, tx *sql.Tx, quiet repository.QuietHours) error {
+	_, err := tx.ExecContext(ctx, `
+        WITH quiet AS (
+            SELECT m.id, local.tz, local.now_local
+            FROM messages m
+            LEFT JOIN pg_timezone_names known ON known.name = m.recipient_timezone
+            CROSS JOIN LATERAL (
+                SELECT COALESCE(known.name, $1) AS tz,
+                       NOW() AT TIME ZONE COALESCE(known.name, $1) AS now_local
+            ) local
+            WHERE m.status = 'queued'
+              AND NOT m.transactional
+              AND (m.send_at IS NULL OR m.send_at <= NOW())
+              AND (m.next_attempt_at IS NULL OR m.next_attempt_at <= NOW())
+              AND (m.expires_at IS NULL OR m.expires_at > NOW())
+              AND CASE WHEN $2::time < $3::time
+                       THEN local.now_local::time >= $2::time AND local.now_local::time < $3::time
+                       ELSE local.now_local::time >= $2::time OR local.now_local::time < $3::time
+                  END
+            FOR UPDATE OF m SKIP LOCKED
+        )
+        UPDATE messages m
+        SET next_attempt_at = (date_trunc('day', quiet.now_local) + $3::time
+                + CASE WHEN quiet.now_local::time >= $3::time THEN INTERVAL '1 day' ELSE INTERVAL '0' END
+            ) AT TIME ZONE quiet.tz,
+            version = m.version + 1
+        FROM quiet
+        WHERE m.id = quiet.id`,
+		quiet.DefaultTimezone, clock(quiet.Start), clock(quiet.End))
+	return err
+}
+
+// clock formats an offset from midnight as a PostgreSQL time literal.
+func clock(offset time.Duration) string {
+	return time.Time{}.Add(offset).Format("15:04:05")
+}
+
+func claimDue(ctx context.Context, tx *sql.Tx, owner string, lease time.Duration, limit int, order string) ([]model.Message, error) {
+	if limit <= 0 {
+		return nil, nil
+	}
+
+	rows, err := tx.QueryContext(ctx, `
+        WITH due AS (
+            SELECT id, COALESCE(next_attempt_at, send_at, created_at) AS due_at
+            FROM messages
+            WHERE status = 'queued'
+              AND (send_at IS NULL OR send_at <= NOW())
+              AND (next_attempt_at IS NULL OR next_attempt_at <= NOW())
+              AND (expires_at IS NULL OR expires_at > NOW())
+            ORDER BY `+order+`
+            LIMIT $3
+            FOR UPDATE SKIP LOCKED
+        ), claimed AS (
+            UPDATE messages m
+            SET status = 'processing',
+                version = m.version + 1,
+                lease_owner = $1,
+                leased_at = NOW(),
+                lease_expires_at = NOW() + make_interval(secs => $2)
+            FROM due
+            WHERE m.id = due.id
+            RETURNING m.*, due.due_at
+        )
+        SELECT `+messageColumns+`
+        FROM claimed
+        ORDER BY `+order, owner, lease.Seconds(), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanMessages(rows)
 }
 
-// MarkAsSent updates a message row with sent details.
-func (r *MessageRepository) MarkAsSent(ctx context.Context, id uuid.UUID, sentAt time.Time) error {
+// ExpireStale moves queued messages past their expires_at to expired and
+// returns how many were moved.
+func (r *MessageRepository) ExpireStale(ctx context.Context) (int64, error) {
 	res, err := r.db.ExecContext(ctx, `
         UPDATE messages
-        SET sent = true, sent_at = $2
-        WHERE id = $1`, id, sentAt)
+        SET status = 'expired',
+            next_attempt_at = NULL,
+            version = version + 1
+        WHERE status = 'queued' AND expires_at <= NOW()`)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// ReleaseClaim returns a leased message to the queue if owner still holds it.
+// A non-nil retryAt holds the message back until then; otherwise it keeps its
+// due time.
+func (r *MessageRepository) ReleaseClaim(ctx context.Context, id uuid.UUID, owner string, retryAt *time.Time) error {
+	_, err := r.db.ExecContext(ctx, `
+        UPDATE messages
+        SET status = 'queued', lease_owner = NULL, lease_expires_at = NULL,
+            next_attempt_at = COALESCE($3, next_attempt_at)
+        WHERE id = $1 AND status = 'processing' AND lease_owner = $2`, id, owner, nullTime(retryAt))
+	return err
+}
+
+// MarkAsSent updates a message row with sent details and closes the journal
+// entry of the accepted call in the same transaction. It returns
+// repository.ErrLeaseLost unless owner still holds the message's lease.
+func (r *MessageRepository) MarkAsSent(ctx context.Context, id uuid.UUID, owner string, sent repository.DeliverySuccess) error {
+	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{})
 	if err != nil {
 		return err
 	}
+	defer tx.Rollback()
 
-	affected, err := res.RowsAffected()
+	res, err := tx.ExecContext(ctx, `
+        UPDATE messages
+        SET status = 'sent',
+            sent_at = $2,
+            remote_message_id = NULLIF($3, ''),
+            route = NULLIF($4, ''),
+            provider = NULLIF($5, ''),
+            attempts = attempts + 1,
+            last_attempt_at = $2,
+            next_attempt_at = NULL,
+            last_error = NULL,
+            last_error_class = NULL,
+            lease_owner = NULL,
+            lease_expires_at = NULL
+        WHERE id = $1 AND status = 'processing' AND lease_owner = $6`, id, sent.SentAt, sent.RemoteID, sent.Route, sent.Provider, owner)
 	if err != nil {
 		return err
 	}
-	if affected == 0 {
-		return sql.ErrNoRows
+	if err := requireLease(res); err != nil {
+		return err
 	}
-	return nil
+
+	if sent.AttemptID != uuid.Nil {
+		outcome := repository.AttemptOutcome{State: model.AttemptAccepted, RemoteID: sent.RemoteID}
+		if err := finishAttempt(ctx, tx, sent.AttemptID, outcome); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// RecordFailure stores a failed delivery attempt and moves the message to
+// failure.Status. It returns repository.ErrLeaseLost unless owner still holds
+// the message's lease.
+func (r *MessageRepository) RecordFailure(ctx context.Context, id uuid.UUID, owner string, failure repository.DeliveryFailure) error {
+	res, err := r.db.ExecContext(ctx, `
+        UPDATE messages
+        SET status = $2,
+            attempts = attempts + CASE WHEN $7 THEN 1 ELSE 0 END,
+            last_attempt_at = $3,
+            next_attempt_at = $4,
+            last_error = $5,
+            last_error_class = NULLIF($6, ''),
+            route = NULLIF($8, ''),
+            provider = NULLIF($9, ''),
+            lease_owner = NULL,
+            lease_expires_at = NULL
+        WHERE id = $1 AND status = 'processing' AND lease_owner = $10`,
+		id, failure.Status, failure.AttemptedAt, nullTime(failure.NextAttemptAt), failure.Error, failure.ErrorClass, failure.CountAttempt, failure.Route, failure.Provider, owner)
+	if err != nil {
+		return err
+	}
+	return requireLease(res)
+}
+
+// FindByID returns the message with the given id.
+func (r *MessageRepository) FindByID(ctx context.Context, id uuid.UUID) (model.Message, error) {
+	return scanMessage(r.db.QueryRowContext(ctx, `
+        SELECT `+messageColumns+`
+        FROM messages
+        WHERE id = $1`, id))
+}
+
+// FindByRemoteID returns the message a provider accepted under remoteID.
+func (r *MessageRepository) FindByRemoteID(ctx context.Context, remoteID string) (model.Message, error) {
+	return scanMessage(r.db.QueryRowContext(ctx, `
+        SELECT `+messageColumns+`
+        FROM messages
+        WHERE remote_message_id = $1
+        ORDER BY sent_at DESC NULLS LAST
+        LIMIT 1`, remoteID))
 }
 
-// ListSent lists sent messages with pagination and counts total.
-func (r *MessageRepository) ListSent(ctx context.Context, offset, limit int) ([]model.Message, int, error) {
+// ListAccepted returns messages that carry a remote id, ordered by (sent_at,
+// id) and starting strictly after the given position, for cache rebuilds.
+func (r *MessageRepository) ListAccepted(ctx context.Context, afterSentAt time.Time, afterID uuid.UUID, limit int) ([]model.Message, error) {
 	rows, err := r.db.QueryContext(ctx, `
-        SELECT id, "to", content, sent, sent_at, created_at
+        SELECT `+messageColumns+`
         FROM messages
-        WHERE sent = true
-        ORDER BY sent_at DESC NULLS LAST, created_at DESC
-        OFFSET $1 LIMIT $2`, offset, limit)
+        WHERE remote_message_id IS NOT NULL
+          AND sent_at IS NOT NULL
+          AND (sent_at, id) > ($1, $2)
+        ORDER BY sent_at, id
+        LIMIT $3`, afterSentAt, afterID, limit)
 	if err != nil {
-		return nil, 0, err
+		return nil, err
 	}
 	defer rows.Close()
 
+	return scanMessages(rows)
+}
+
+// RecordReceipt applies a carrier delivery receipt to a sent message. Receipts
+// older than one already applied are ignored so out-of-order callbacks cannot
+// regress the status; the result reports whether the row changed.
+func (r *MessageRepository) RecordReceipt(ctx context.Context, id uuid.UUID, receipt repository.DeliveryReceipt) (bool, error) {
+	res, err := r.db.ExecContext(ctx, `
+        UPDATE messages
+        SET status = $2,
+            receipt_at = $3,
+            carrier_error_code = NULLIF($4, '')
+        WHERE id = $1
+          AND (status = 'sent'
+               OR (status IN ('delivered', 'undelivered') AND receipt_at <= $3))`,
+		id, receipt.Status, receipt.At, receipt.ErrorCode)
+	if err != nil {
+		return false, err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected > 0, nil
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanMessage(row rowScanner) (model.Message, error) {
+	var msg model.Message
+	var lastError, lastErrorClass, remoteID, route, provider, carrierError, recipientTimezone, idempotencyKey, fingerprint sql.NullString
+	var lastAttemptAt, nextAttemptAt, sentAt, receiptAt, sendAt, expiresAt, occurrenceAt sql.NullTime
+	var scheduleID uuid.NullUUID
+	if err := row.Scan(
+		&msg.ID, &msg.To, &msg.Content, &msg.Status, &msg.Priority, &msg.Attempts, &lastError, &lastErrorClass, &lastAttemptAt, &nextAttemptAt,
+		&remoteID, &route, &provider, &sentAt, &receiptAt, &carrierError, &sendAt, &expiresAt, &recipientTimezone, &msg.RecipientTimezoneInferred, &msg.Transactional, &idempotencyKey, &fingerprint, &scheduleID, &occurrenceAt, &msg.Version, &msg.CreatedAt,
+	); err != nil {
+		return model.Message{}, err
+	}
+	msg.IdempotencyKey = idempotencyKey.String
+	msg.IdempotencyFingerprint = fingerprint.String
+	msg.LastError = lastError.String
+	msg.LastErrorClass = lastErrorClass.String
+	msg.LastAttemptAt = timePtr(lastAttemptAt)
+	msg.NextAttemptAt = timePtr(nextAttemptAt)
+	msg.RemoteMessageID = remoteID.String
+	msg.Route = route.String
+	msg.Provider = provider.String
+	msg.SentAt = timePtr(sentAt)
+	msg.ReceiptAt = timePtr(receiptAt)
+	msg.CarrierError = carrierError.String
+	msg.SendAt = timePtr(sendAt)
+	msg.ExpiresAt = timePtr(expiresAt)
+	msg.RecipientTimezone = recipientTimezone.String
+	msg.OccurrenceAt = timePtr(occurrenceAt)
+	if scheduleID.Valid {
+		msg.ScheduleID = &scheduleID.UUID
+	}
+	return msg, nil
+}
+
+func scanMessages(rows *sql.Rows) ([]model.Message, error) {
 	var messages []model.Message
 	for rows.Next() {
-		var msg model.Message
-		var sentAt sql.NullTime
-		if err := rows.Scan(&msg.ID, &msg.To, &msg.Content, &msg.Sent, &sentAt, &msg.CreatedAt); err != nil {
-			return nil, 0, err
-		}
-		if sentAt.Valid {
-			ts := sentAt.Time
-			msg.SentAt = &ts
+		msg, err := scanMessage(rows)
+		if err != nil {
+			return nil, err
 		}
 		messages = append(messages, msg)
 	}
-	if err := rows.Err(); err != nil {
-		return nil, 0, err
+	return messages, rows.Err()
+}
+
+func requireAffected(res sql.Result) error {
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
 	}
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
 
-	var total int
-	if err := r.db.QueryRowContext(ctx, `SELECT COUNT(1) FROM messages WHERE sent = true`).Scan(&total); err != nil {
-		return nil, 0, err
+// requireLease maps an update that matched no leased row to
+// repository.ErrLeaseLost.
+func requireLease(res sql.Result) error {
+	err := requireAffected(res)
+	if errors.Is(err, sql.ErrNoRows) {
+		return repository.ErrLeaseLost
 	}
+	return err
+}
 
-	return messages, total, nil
+func timePtr(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	ts := t.Time
+	return &ts
+}
+
+func nullTime(t *time.Time) sql.NullTime {
+	if t == nil {
+		return sql.NullTime{}
+	}
+	return sql.NullTime{Time: *t, Valid: true}
+}
+
+func nullString(s *string) sql.NullString {
+	if s == nil {
+		return sql.NullString{}
+	}
+	return sql.NullString{String: *s, Valid: true}
+}
+
+func nullZone(zone string) sql.NullString {
+	return sql.NullString{String: zone, Valid: zone != ""}
+}
+
+func nullInt(i *int) sql.NullInt32 {
+	if i == nil {
+		return sql.NullInt32{}
+	}
+	return sql.NullInt32{Int32: int32(*i), Valid: true}
 }
@@ -0,0 +1,242 @@
+package postgres
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"automessaging/internal/model"
+	"automessaging/internal/repository"
+)
+
+func TestClaimNextUnsentSkipsRowsLockedByAnotherClaimer(t *testing.T) {
+	database := testDB(t)
+	repo := NewMessageRepository(database)
+	ctx := context.Background()
+	msgs := createQueued(t, repo, 3)
+
+	// Hold a row lock the way an in-flight claim transaction does.
+	tx, err := database.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+	if _, err := tx.ExecContext(ctx, `SELECT id FROM messages WHERE id = $1 FOR UPDATE`, msgs[0].ID); err != nil {
+		t.Fatal(err)
+	}
+
+	claimCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	claimed, err := repo.ClaimNextUnsent(claimCtx, repository.ClaimRequest{Owner: "b", Lease: time.Minute, Limit: 10})
+	if err != nil {
+		t.Fatalf("claim while a row is locked: %v", err)
+	}
+	if len(claimed) != 2 {
+		t.Fatalf("claimed %d messages, want the 2 unlocked ones", len(claimed))
+	}
+	for _, msg := range claimed {
+		if msg.ID == msgs[0].ID {
+			t.Fatal("claimed the row locked by the other claimer")
+		}
+	}
+}
+
+func TestConcurrentClaimersNeverShareAMessage(t *testing.T) {
+	repo := NewMessageRepository(testDB(t))
+	ctx := context.Background()
+	const total = 50
+	createQueued(t, repo, total)
+
+	var mu sync.Mutex
+	owners := make(map[uuid.UUID]string)
+	var wg sync.WaitGroup
+	for _, owner := range []string{"a", "b"} {
+		wg.Go(func() {
+			for {
+				claimed, err := repo.ClaimNextUnsent(ctx, repository.ClaimRequest{Owner: owner, Lease: time.Minute, Limit: 5})
+				if err != nil {
+					t.Errorf("%s: claim: %v", owner, err)
+					return
+				}
+				if len(claimed) == 0 {
+					return
+				}
+				mu.Lock()
+				for _, msg := range claimed {
+					if previous, ok := owners[msg.ID]; ok {
+						t.Errorf("message %s claimed by both %s and %s", msg.ID, previous, owner)
+					}
+					owners[msg.ID] = owner
+				}
+				mu.Unlock()
+			}
+		})
+	}
+	wg.Wait()
+
+	if len(owners) != total {
+		t.Fatalf("claimed %d distinct messages, want %d", len(owners), total)
+	}
+}
+
+func TestReapExpiredLeasesUsesTheAttemptJournal(t *testing.T) {
+	database := testDB(t)
+	repo := NewMessageRepository(database)
+	ctx := context.Background()
+	msgs := createQueued(t, repo, 3)
+	accepted, dispatching, untried := msgs[0].ID, msgs[1].ID, msgs[2].ID
+
+	if _, err := repo.ClaimNextUnsent(ctx, repository.ClaimRequest{Owner: "crashed", Lease: time.Minute, Limit: 3}); err != nil {
+		t.Fatal(err)
+	}
+	attemptID, err := repo.BeginAttempt(ctx, accepted, "crashed", "primary")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.FinishAttempt(ctx, attemptID, repository.AttemptOutcome{State: model.AttemptAccepted, RemoteID: "remote-1"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.BeginAttempt(ctx, dispatching, "crashed", "primary"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Nothing is reaped while the lease is still valid.
+	if result, err := repo.ReapExpiredLeases(ctx); err != nil || result != (repository.ReapResult{}) {
+		t.Fatalf("reap of live leases = %+v, %v", result, err)
+	}
+
+	if _, err := database.ExecContext(ctx, `UPDATE messages SET lease_expires_at = NOW() - INTERVAL '1 second'`); err != nil {
+		t.Fatal(err)
+	}
+	result, err := repo.ReapExpiredLeases(ctx)
+	if err != nil {
+		t.Fatalf("reap: %v", err)
+	}
+	if result != (repository.ReapResult{Reconciled: 1, Unknown: 1, Requeued: 1}) {
+		t.Fatalf("reap result = %+v", result)
+	}
+
+	for id, want := range map[uuid.UUID]model.MessageStatus{accepted: model.StatusSent, dispatching: model.StatusFailed, untried: model.StatusQueued} {
+		msg, err := repo.FindByID(ctx, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if msg.Status != want {
+			t.Errorf("message %s is %s, want %s", id, msg.Status, want)
+		}
+	}
+	var leased int
+	if err := database.QueryRowContext(ctx, `SELECT COUNT(*) FROM messages WHERE lease_owner IS NOT NULL`).Scan(&leased); err != nil || leased != 0 {
+		t.Fatalf("%d messages still leased, err %v", leased, err)
+	}
+}
+
+func TestClaimNextUnsentPrefersPriorityAndKeepsAnOldestFirstShare(t *testing.T) {
+	database := testDB(t)
+	repo := NewMessageRepository(database)
+	ctx := context.Background()
+	msgs := createQueued(t, repo, 4)
+	// oldest and older are bulk; urgent and newest outrank them.
+	oldest, older, urgent, newest := msgs[0].ID, msgs[1].ID, msgs[2].ID, msgs[3].ID
+	for i, id := range []uuid.UUID{oldest, older, urgent, newest} {
+		priority := model.MinPriority
+		if id == urgent || id == newest {
+			priority = model.MaxPriority
+		}
+		if _, err := database.ExecContext(ctx, `
+            UPDATE messages SET priority = $2, created_at = NOW() - make_interval(mins => $3)
+            WHERE id = $1`, id, priority, 10-i); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	claimed, err := repo.ClaimNextUnsent(ctx, repository.ClaimRequest{Owner: "a", Lease: time.Minute, Limit: 3, OldestFirst: 1})
+	if err != nil {
+		t.Fatalf("claim: %v", err)
+	}
+	got := make([]uuid.UUID, 0, len(claimed))
+	for _, msg := range claimed {
+		got = append(got, msg.ID)
+	}
+	want := []uuid.UUID{urgent, newest, oldest}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] || got[2] != want[2] {
+		t.Fatalf("claimed %v, want %v", got, want)
+	}
+}
+
+func TestClaimNextUnsentDefersQuietHoursInTheRecipientZone(t *testing.T) {
+	database := testDB(t)
+	repo := NewMessageRepository(database)
+	ctx := context.Background()
+
+	// A one-hour window around the current time in Tokyo, which New York and
+	// the UTC default are far outside of.
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now().In(tokyo)
+	sinceMidnight := now.Sub(time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, tokyo))
+	quiet := repository.QuietHours{
+		Start:           (sinceMidnight - 30*time.Minute + 24*time.Hour) % (24 * time.Hour),
+		End:             (sinceMidnight + 30*time.Minute) % (24 * time.Hour),
+		DefaultTimezone: "UTC",
+	}
+
+	create := func(zone string, transactional bool) uuid.UUID {
+		msg := model.Message{To: "+905551112233", Content: "hello", Priority: model.DefaultPriority, RecipientTimezone: zone, Transactional: transactional}
+		if err := repo.Create(ctx, &msg); err != nil {
+			t.Fatal(err)
+		}
+		return msg.ID
+	}
+	quietTokyo := create("Asia/Tokyo", false)
+	otpTokyo := create("Asia/Tokyo", true)
+	newYork := create("America/New_York", false)
+	defaultZone := create("", false)
+	// Stored before zones were validated; it must fall back to the default
+	// instead of failing the claim.
+	badZone := create("", false)
+	if _, err := database.ExecContext(ctx, `UPDATE messages SET recipient_timezone = 'Local' WHERE id = $1`, badZone); err != nil {
+		t.Fatal(err)
+	}
+
+	claimed, err := repo.ClaimNextUnsent(ctx, repository.ClaimRequest{Owner: "a", Lease: time.Minute, Limit: 10, QuietHours: &quiet})
+	if err != nil {
+		t.Fatalf("claim: %v", err)
+	}
+	got := make(map[uuid.UUID]bool, len(claimed))
+	for _, msg := range claimed {
+		got[msg.ID] = true
+	}
+	if len(got) != 4 || !got[otpTokyo] || !got[newYork] || !got[defaultZone] || !got[badZone] {
+		t.Fatalf("claimed %v, want every message but the quiet one in Tokyo", got)
+	}
+
+	deferred, err := repo.FindByID(ctx, quietTokyo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantAt := now.Add(30 * time.Minute)
+	if deferred.Status != model.StatusQueued || deferred.NextAttemptAt == nil ||
+		deferred.NextAttemptAt.Before(wantAt.Add(-time.Minute)) || deferred.NextAttemptAt.After(wantAt.Add(time.Minute)) {
+		t.Fatalf("quiet message is %s with next attempt %v, want queued until about %v", deferred.Status, deferred.NextAttemptAt, wantAt)
+	}
+}
+
+// createQueued stores n due messages and returns them in creation order.
+func createQueued(t *testing.T, repo *MessageRepository, n int) []model.Message {
+	t.Helper()
+	msgs := make([]model.Message, n)
+	for i := range msgs {
+		msgs[i] = model.Message{To: "+905551112233", Content: "hello", Priority: model.DefaultPriority}
+	}
+	created, err := repo.CreateBatch(context.Background(), msgs)
+	if err != nil {
+		t.Fatalf("create messages: %v", err)
+	}
+	return created
+}
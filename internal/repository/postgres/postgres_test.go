@@ -0,0 +1,37 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"testing"
+
+	"automessaging/internal/db"
+)
+
+// testDB connects to the database named by TEST_DATABASE_DSN, applies the
+// migrations and empties the message tables. Tests using it are skipped when
+// the variable is unset; point it at a disposable database because every
+// test truncates it.
+func testDB(t *testing.T) *sql.DB {
+	t.Helper()
+	dsn := os.Getenv("TEST_DATABASE_DSN")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_DSN is not set")
+	}
+
+	database, err := sql.Open("pgx", dsn)
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	t.Cleanup(func() { database.Close() })
+
+	ctx := context.Background()
+	if err := db.RunMigrations(ctx, database, "../../../migrations"); err != nil {
+		t.Fatalf("run migrations: %v", err)
+	}
+	if _, err := database.ExecContext(ctx, `TRUNCATE messages, delivery_attempts, message_batches, recurring_schedules CASCADE`); err != nil {
+		t.Fatalf("truncate tables: %v", err)
+	}
+	return database
+}
@@ -0,0 +1,216 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+
+	"automessaging/internal/model"
+	"automessaging/internal/repository"
+)
+
+var _ repository.ScheduleRepository = (*ScheduleRepository)(nil)
+
+const scheduleColumns = `id, "to", content, cron_expression, timezone, priority, ttl_seconds, starts_at, ends_at, next_occurrence_at, active, created_at, recipient_timezone, recipient_timezone_inferred`
+
+// maxOccurrencesPerPass bounds how many messages one pass creates for a
+// single schedule within the lookahead window. The rest follows on later passes.
+const maxOccurrencesPerPass = 100
+
+// ScheduleRepository provides PostgreSQL backed recurring schedule operations.
+type ScheduleRepository struct {
+	db *sql.DB
+}
+
+// NewScheduleRepository creates a new repository instance.
+func NewScheduleRepository(db *sql.DB) *ScheduleRepository {
+	return &ScheduleRepository{db: db}
+}
+
+// CreateSchedule inserts schedule and populates generated fields.
+func (r *ScheduleRepository) CreateSchedule(ctx context.Context, schedule *model.RecurringSchedule) error {
+	return r.db.QueryRowContext(ctx, `
+        INSERT INTO recurring_schedules ("to", content, cron_expression, timezone, priority, ttl_seconds, starts_at, ends_at, next_occurrence_at, recipient_timezone, recipient_timezone_inferred)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+        RETURNING id, active, created_at`,
+		schedule.To, schedule.Content, schedule.CronExpression, schedule.Timezone, schedule.Priority,
+		nullInt(schedule.TTLSeconds), schedule.StartsAt, nullTime(schedule.EndsAt), nullTime(schedule.NextOccurrenceAt),
+		nullZone(schedule.RecipientTimezone), schedule.RecipientTimezoneInferred,
+	).Scan(&schedule.ID, &schedule.Active, &schedule.CreatedAt)
+}
+
+// FindScheduleByID returns the schedule with the given id.
+func (r *ScheduleRepository) FindScheduleByID(ctx context.Context, id uuid.UUID) (model.RecurringSchedule, error) {
+	return scanSchedule(r.db.QueryRowContext(ctx, `
+        SELECT `+scheduleColumns+`
+        FROM recurring_schedules
+        WHERE id = $1`, id))
+}
+
+// ListSchedules lists schedules, newest first.
+func (r *ScheduleRepository) ListSchedules(ctx context.Context, offset, limit int) ([]model.RecurringSchedule, int, error) {
+	rows, err := r.db.QueryContext(ctx, `
+        SELECT `+scheduleColumns+`
+        FROM recurring_schedules
+        ORDER BY created_at DESC, id DESC
+        OFFSET $1 LIMIT $2`, offset, limit)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	schedules, err := scanSchedules(rows)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var total int
+	if err := r.db.QueryRowContext(ctx, `SELECT COUNT(1) FROM recurring_schedules`).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	return schedules, total, nil
+}
+
+// DeactivateSchedule stops a schedule from producing further messages.
+// Messages already created are left alone.
+func (r *ScheduleRepository) DeactivateSchedule(ctx context.Context, id uuid.UUID) (model.RecurringSchedule, error) {
+	return scanSchedule(r.db.QueryRowContext(ctx, `
+        UPDATE recurring_schedules
+        SET active = false
+        WHERE id = $1
+        RETURNING `+scheduleColumns, id))
+}
+
+// MaterializeDue locks due schedules with SKIP LOCKED, so concurrent replicas
+// work on disjoint schedules, and inserts their messages and the advanced
+// next occurrence in one transaction. The unique (schedule_id, occurrence_at)
+// index makes a repeated occurrence a no-op rather than a duplicate message.
+// Occurrences before missedBefore, e.g. from downtime, are skipped rather
+// than sent late.
+func (r *ScheduleRepository) MaterializeDue(ctx context.Context, missedBefore, until time.Time, limit int, next repository.NextOccurrence) (repository.MaterializeResult, error) {
+	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return repository.MaterializeResult{}, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, `
+        SELECT `+scheduleColumns+`
+        FROM recurring_schedules
+        WHERE active AND next_occurrence_at <= $1
+        ORDER BY next_occurrence_at
+        LIMIT $2
+        FOR UPDATE SKIP LOCKED`, until, limit)
+	if err != nil {
+		return repository.MaterializeResult{}, err
+	}
+	schedules, err := scanSchedules(rows)
+	rows.Close()
+	if err != nil {
+		return repository.MaterializeResult{}, err
+	}
+
+	result := repository.MaterializeResult{Schedules: len(schedules)}
+	for _, schedule := range schedules {
+		occurrence := schedule.NextOccurrenceAt
+		if occurrence != nil && occurrence.Before(missedBefore) {
+			occurrence = skipMissed(schedule, missedBefore, next)
+			result.Skipped++
+		}
+		for n := 0; occurrence != nil && !occurrence.After(until) && n < maxOccurrencesPerPass; n++ {
+			inserted, err := insertOccurrence(ctx, tx, schedule, *occurrence)
+			if err != nil {
+				return repository.MaterializeResult{}, err
+			}
+			result.Messages += inserted
+
+			following, ok := next(schedule, *occurrence)
+			occurrence = nil
+			if ok {
+				occurrence = &following
+			}
+		}
+
+		if _, err := tx.ExecContext(ctx, `
+            UPDATE recurring_schedules
+            SET next_occurrence_at = $2
+            WHERE id = $1`, schedule.ID, nullTime(occurrence)); err != nil {
+			return repository.MaterializeResult{}, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return repository.MaterializeResult{}, err
+	}
+	return result, nil
+}
+
+// skipMissed returns the first occurrence of schedule at or after
+// missedBefore, or nil when the schedule ended in the meantime. Cron
+// occurrences fall on whole seconds and next is exclusive, hence the step back.
+func skipMissed(schedule model.RecurringSchedule, missedBefore time.Time, next repository.NextOccurrence) *time.Time {
+	following, ok := next(schedule, missedBefore.Add(-time.Second))
+	if !ok {
+		return nil
+	}
+	return &following
+}
+
+// insertOccurrence queues one occurrence of schedule with the schedule's
+// recipient zone, so quiet hours and later edits treat it like any message.
+func insertOccurrence(ctx context.Context, tx *sql.Tx, schedule model.RecurringSchedule, occurrence time.Time) (int, error) {
+	var expiresAt *time.Time
+	if schedule.TTLSeconds != nil {
+		at := occurrence.Add(time.Duration(*schedule.TTLSeconds) * time.Second)
+		expiresAt = &at
+	}
+
+	res, err := tx.ExecContext(ctx, `
+        INSERT INTO messages ("to", content, priority, send_at, expires_at, recipient_timezone, recipient_timezone_inferred, schedule_id, occurrence_at)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $4)
+        ON CONFLICT (schedule_id, occurrence_at) WHERE schedule_id IS NOT NULL DO NOTHING`,
+		schedule.To, schedule.Content, schedule.Priority, occurrence, nullTime(expiresAt),
+		nullZone(schedule.RecipientTimezone), schedule.RecipientTimezoneInferred, schedule.ID)
+	if err != nil {
+		return 0, err
+	}
+	affected, err := res.RowsAffected()
+	return int(affected), err
+}
+
+func scanSchedule(row rowScanner) (model.RecurringSchedule, error) {
+	var schedule model.RecurringSchedule
+	var ttlSeconds sql.NullInt32
+	var endsAt, nextOccurrenceAt sql.NullTime
+	var recipientTimezone sql.NullString
+	if err := row.Scan(
+		&schedule.ID, &schedule.To, &schedule.Content, &schedule.CronExpression, &schedule.Timezone, &schedule.Priority,
+		&ttlSeconds, &schedule.StartsAt, &endsAt, &nextOccurrenceAt, &schedule.Active, &schedule.CreatedAt,
+		&recipientTimezone, &schedule.RecipientTimezoneInferred,
+	); err != nil {
+		return model.RecurringSchedule{}, err
+	}
+	if ttlSeconds.Valid {
+		ttl := int(ttlSeconds.Int32)
+		schedule.TTLSeconds = &ttl
+	}
+	schedule.EndsAt = timePtr(endsAt)
+	schedule.NextOccurrenceAt = timePtr(nextOccurrenceAt)
+	schedule.RecipientTimezone = recipientTimezone.String
+	return schedule, nil
+}
+
+func scanSchedules(rows *sql.Rows) ([]model.RecurringSchedule, error) {
+	var schedules []model.RecurringSchedule
+	for rows.Next() {
+		schedule, err := scanSchedule(rows)
+		if err != nil {
+			return nil, err
+		}
+		schedules = append(schedules, schedule)
+	}
+	return schedules, rows.Err()
+}
@@ -0,0 +1,37 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+
+	"automessaging/internal/model"
+)
+
+// NextOccurrence returns the first occurrence of schedule strictly after
+// after, or false when the schedule has no further occurrences.
+type NextOccurrence func(schedule model.RecurringSchedule, after time.Time) (time.Time, bool)
+
+// MaterializeResult reports one materialization batch: how many schedules
+// were advanced, how many messages they created and how many of them skipped
+// missed occurrences.
+type MaterializeResult struct {
+	Schedules int
+	Messages  int
+	Skipped   int
+}
+
+// ScheduleRepository defines the database operations required for recurring
+// schedules.
+type ScheduleRepository interface {
+	CreateSchedule(ctx context.Context, schedule *model.RecurringSchedule) error
+	FindScheduleByID(ctx context.Context, id uuid.UUID) (model.RecurringSchedule, error)
+	ListSchedules(ctx context.Context, offset, limit int) ([]model.RecurringSchedule, int, error)
+	DeactivateSchedule(ctx context.Context, id uuid.UUID) (model.RecurringSchedule, error)
+	// MaterializeDue enqueues a message for every occurrence between
+	// missedBefore and until of at most limit active schedules and advances
+	// each schedule with next. Occurrences before missedBefore are skipped.
+	// Schedules being materialized by another caller are left alone.
+	MaterializeDue(ctx context.Context, missedBefore, until time.Time, limit int, next NextOccurrence) (MaterializeResult, error)
+}
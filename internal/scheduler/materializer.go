@@ -0,0 +1,58 @@
+package scheduler
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"automessaging/internal/recurrence"
+	"automessaging/internal/repository"
+)
+
+// materializeBatch bounds how many schedules one pass locks at a time.
+const materializeBatch = 100
+
+// Materializer turns recurring schedules into concrete messages. Occurrences
+// are created up to lookahead ahead of now with send_at set to the
+// occurrence, so a message is ready before the pass that should send it.
+// Each occurrence is created exactly once, whichever replica gets to it.
+// Occurrences more than lookahead in the past, i.e. missed while no replica
+// was running, are skipped: a reminder is worth less late, and a week of
+// downtime must not release a week of daily messages at once. Anything later
+// than that is still created, so a slow pass does not drop an occurrence.
+type Materializer struct {
+	repo      repository.ScheduleRepository
+	lookahead time.Duration
+	logger    *log.Logger
+}
+
+// NewMaterializer builds a Materializer. lookahead is normally the scheduler
+// interval.
+func NewMaterializer(repo repository.ScheduleRepository, lookahead time.Duration, logger *log.Logger) *Materializer {
+	if logger == nil {
+		logger = log.New(log.Writer(), "materializer ", log.LstdFlags)
+	}
+	return &Materializer{repo: repo, lookahead: lookahead, logger: logger}
+}
+
+// Materialize creates the messages of every occurrence due within the
+// lookahead window, in batches until no due schedule is left.
+func (m *Materializer) Materialize(ctx context.Context) error {
+	now := time.Now().UTC()
+	missedBefore, until := now.Add(-m.lookahead), now.Add(m.lookahead)
+	for {
+		result, err := m.repo.MaterializeDue(ctx, missedBefore, until, materializeBatch, recurrence.NextOccurrence)
+		if err != nil {
+			return err
+		}
+		if result.Messages > 0 {
+			m.logger.Printf("created %d messages from %d recurring schedules", result.Messages, result.Schedules)
+		}
+		if result.Skipped > 0 {
+			m.logger.Printf("skipped missed occurrences of %d recurring schedules", result.Skipped)
+		}
+		if result.Schedules < materializeBatch {
+			return nil
+		}
+	}
+}
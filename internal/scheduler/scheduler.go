@@ -15,13 +15,17 @@ type Processor interface {
 
 // Scheduler drives periodic message processing without cron.
 type Scheduler struct {
-	processor Processor
-	interval  time.Duration
-	logger    *log.Logger
-
-	mu      sync.Mutex
-	running bool
-	cancel  context.CancelFunc
+	processor    Processor
+	materializer *Materializer
+	interval     time.Duration
+	logger       *log.Logger
+
+	mu       sync.Mutex
+	running  bool
+	stopping bool
+	cancel   context.CancelFunc
+	stop     chan struct{}
+	done     chan struct{}
 }
 
 // ErrAlreadyRunning is emitted when start is called twice.
@@ -41,6 +45,12 @@ func New(processor Processor, interval time.Duration, logger *log.Logger) *Sched
 	return &Scheduler{processor: processor, interval: interval, logger: logger}
 }
 
+// UseMaterializer makes every iteration create the messages of due recurring
+// schedules before processing. It must be called before Start.
+func (s *Scheduler) UseMaterializer(m *Materializer) {
+	s.materializer = m
+}
+
 // Start begins the background loop.
 func (s *Scheduler) Start(ctx context.Context) error {
 	s.mu.Lock()
@@ -56,27 +66,48 @@ func (s *Scheduler) Start(ctx context.Context) error {
 
 	loopCtx, cancel := context.WithCancel(ctx)
 	s.cancel = cancel
+	s.stop = make(chan struct{})
+	s.done = make(chan struct{})
 	s.running = true
 
-	go s.run(loopCtx)
+	go s.run(loopCtx, s.stop, s.done)
 	s.logger.Println("scheduler started")
 
 	return nil
 }
 
-// Stop cancels the loop.
-func (s *Scheduler) Stop() error {
+// Stop ends the loop and waits for the current iteration, so provider calls
+// in flight can record their outcome instead of being reaped as unknown. If
+// ctx ends first the iteration is cancelled, Stop waits for it to unwind and
+// returns ctx's error. The scheduler reports running until the loop has
+// exited, so Start cannot launch a second loop next to the draining one.
+func (s *Scheduler) Stop(ctx context.Context) error {
 	s.mu.Lock()
-	defer s.mu.Unlock()
-
-	if !s.running {
+	if !s.running || s.stopping {
+		s.mu.Unlock()
 		return ErrNotRunning
 	}
-
-	s.cancel()
-	s.running = false
-	s.logger.Println("scheduler stopped")
-	return nil
+	close(s.stop)
+	s.stopping = true
+	cancel, done := s.cancel, s.done
+	s.mu.Unlock()
+
+	defer func() {
+		cancel()
+		s.mu.Lock()
+		s.running, s.stopping = false, false
+		s.mu.Unlock()
+	}()
+	select {
+	case <-done:
+		s.logger.Println("scheduler stopped")
+		return nil
+	case <-ctx.Done():
+		cancel()
+		<-done
+		s.logger.Println("scheduler stopped; in-flight iteration cancelled")
+		return ctx.Err()
+	}
 }
 
 // IsRunning reports the scheduler state.
@@ -86,7 +117,8 @@ func (s *Scheduler) IsRunning() bool {
 	return s.running
 }
 
-func (s *Scheduler) run(ctx context.Context) {
+func (s *Scheduler) run(ctx context.Context, stop <-chan struct{}, done chan<- struct{}) {
+	defer close(done)
 	ticker := time.NewTicker(s.interval)
 	defer ticker.Stop()
 
@@ -96,13 +128,27 @@ func (s *Scheduler) run(ctx context.Context) {
 		select {
 		case <-ctx.Done():
 			return
+		case <-stop:
+			return
 		case <-ticker.C:
+			// A tick and a stop can be ready together; stopping wins.
+			select {
+			case <-stop:
+				return
+			default:
+			}
 			s.execute(ctx)
 		}
 	}
 }
 
 func (s *Scheduler) execute(ctx context.Context) {
+	if s.materializer != nil {
+		if err := s.materializer.Materialize(ctx); err != nil && !errors.Is(err, context.Canceled) {
+			s.logger.Printf("materializing recurring schedules failed: %v", err)
+		}
+	}
+
 	if err := s.processor.ProcessPendingMessages(ctx); err != nil {
 		if errors.Is(err, context.Canceled) {
 			return
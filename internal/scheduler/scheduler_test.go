@@ -0,0 +1,120 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"testing"
+	"time"
+)
+
+func TestStopWaitsForRunningIteration(t *testing.T) {
+	processor := newBlockingProcessor()
+	s := New(processor, time.Hour, log.New(io.Discard, "", 0))
+	if err := s.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	<-processor.started
+
+	stopped := make(chan error, 1)
+	go func() { stopped <- s.Stop(context.Background()) }()
+
+	select {
+	case err := <-stopped:
+		t.Fatalf("Stop returned %v before the iteration finished", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(processor.release)
+	if err := <-stopped; err != nil {
+		t.Fatalf("Stop: %v", err)
+	}
+	if err := <-processor.result; err != nil {
+		t.Fatalf("iteration context ended early: %v", err)
+	}
+}
+
+func TestStartIsRejectedWhileStopDrains(t *testing.T) {
+	processor := newBlockingProcessor()
+	s := New(processor, time.Hour, log.New(io.Discard, "", 0))
+	if err := s.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	<-processor.started
+
+	stopped := make(chan error, 1)
+	go func() { stopped <- s.Stop(context.Background()) }()
+	for {
+		s.mu.Lock()
+		stopping := s.stopping
+		s.mu.Unlock()
+		if stopping {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	if err := s.Start(context.Background()); !errors.Is(err, ErrAlreadyRunning) {
+		t.Fatalf("Start during drain = %v, want %v", err, ErrAlreadyRunning)
+	}
+	if err := s.Stop(context.Background()); !errors.Is(err, ErrNotRunning) {
+		t.Fatalf("second Stop = %v, want %v", err, ErrNotRunning)
+	}
+
+	close(processor.release)
+	if err := <-stopped; err != nil {
+		t.Fatalf("Stop: %v", err)
+	}
+	if s.IsRunning() {
+		t.Fatal("scheduler still reports running")
+	}
+}
+
+func TestStopCancelsIterationWhenContextEnds(t *testing.T) {
+	processor := newBlockingProcessor()
+	s := New(processor, time.Hour, log.New(io.Discard, "", 0))
+	if err := s.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	<-processor.started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := s.Stop(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Stop = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if err := <-processor.result; !errors.Is(err, context.Canceled) {
+		t.Fatalf("iteration ended with %v, want %v", err, context.Canceled)
+	}
+	if s.IsRunning() {
+		t.Fatal("scheduler still reports running")
+	}
+}
+
+// blockingProcessor holds its first iteration until release is closed or the
+// context ends, and reports which happened on result.
+type blockingProcessor struct {
+	started chan struct{}
+	release chan struct{}
+	result  chan error
+}
+
+func newBlockingProcessor() *blockingProcessor {
+	return &blockingProcessor{
+		started: make(chan struct{}),
+		release: make(chan struct{}),
+		result:  make(chan error, 1),
+	}
+}
+
+func (p *blockingProcessor) ProcessPendingMessages(ctx context.Context) error {
+	close(p.started)
+	select {
+	case <-p.release:
+		p.result <- nil
+	case <-ctx.Done():
+		p.result <- ctx.Err()
+	}
+	return ctx.Err()
+}
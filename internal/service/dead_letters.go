@@ -0,0 +1,93 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"automessaging/internal/model"
+	"automessaging/internal/repository"
+)
+
+// ErrNotFound is returned when the requested message does not exist in the expected state.
+var ErrNotFound = errors.New("message not found")
+
+// DeadLetterFilter selects dead-lettered (failed) messages. Empty fields match everything.
+type DeadLetterFilter struct {
+	IDs           []uuid.UUID `json:"ids,omitempty"`
+	To            string      `json:"to,omitempty"`
+	ErrorContains string      `json:"error_contains,omitempty"`
+	FailedAfter   *time.Time  `json:"failed_after,omitempty"`
+	FailedBefore  *time.Time  `json:"failed_before,omitempty"`
+}
+
+// DeadLettersResult captures paginated dead-lettered messages.
+type DeadLettersResult struct {
+	Messages []model.Message `json:"messages"`
+	Total    int             `json:"total"`
+	Page     int             `json:"page"`
+	Limit    int             `json:"limit"`
+}
+
+// ListDeadLetters returns paginated messages that exhausted their retries.
+func (s *MessageService) ListDeadLetters(ctx context.Context, filter DeadLetterFilter, page, limit int) (DeadLettersResult, error) {
+	page, limit = normalizePage(page, limit)
+
+	items, total, err := s.deps.repo.ListDeadLetters(ctx, filter.toRepository(), (page-1)*limit, limit)
+	if err != nil {
+		return DeadLettersResult{}, err
+	}
+
+	return DeadLettersResult{
+		Messages: items,
+		Total:    total,
+		Page:     page,
+		Limit:    limit,
+	}, nil
+}
+
+// Requeue returns a single dead-lettered message to the queue.
+func (s *MessageService) Requeue(ctx context.Context, id uuid.UUID) error {
+	if err := s.deps.repo.Requeue(ctx, id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("%w: no failed message with id %s", ErrNotFound, id)
+		}
+		return err
+	}
+	return nil
+}
+
+// RequeueDeadLetters returns every dead-lettered message matching filter to
+// the queue. Requeueing everything must be requested explicitly with all set
+// to true.
+func (s *MessageService) RequeueDeadLetters(ctx context.Context, filter DeadLetterFilter, all bool) (int64, error) {
+	repoFilter := filter.toRepository()
+	if repoFilter.IsEmpty() && !all {
+		return 0, fmt.Errorf("%w: requeueing all dead letters requires all=true", ErrValidation)
+	}
+	return s.deps.repo.RequeueDeadLetters(ctx, repoFilter)
+}
+
+// PurgeDeadLetters deletes dead-lettered messages matching filter. Purging
+// everything must be requested explicitly with all set to true.
+func (s *MessageService) PurgeDeadLetters(ctx context.Context, filter DeadLetterFilter, all bool) (int64, error) {
+	repoFilter := filter.toRepository()
+	if repoFilter.IsEmpty() && !all {
+		return 0, fmt.Errorf("%w: purging all dead letters requires all=true", ErrValidation)
+	}
+	return s.deps.repo.PurgeDeadLetters(ctx, repoFilter)
+}
+
+func (f DeadLetterFilter) toRepository() repository.DeadLetterFilter {
+	return repository.DeadLetterFilter{
+		IDs:           f.IDs,
+		To:            f.To,
+		ErrorContains: f.ErrorContains,
+		FailedAfter:   f.FailedAfter,
+		FailedBefore:  f.FailedBefore,
+	}
+}
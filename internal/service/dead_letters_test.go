@@ -0,0 +1,43 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"testing"
+
+	"automessaging/internal/repository"
+)
+
+func TestRequeueDeadLettersRequiresConfirmationWithoutFilter(t *testing.T) {
+	repo := &requeueRepo{}
+	svc := NewMessageService(Dependencies{Repo: repo}, MessageServiceOptions{Logger: log.New(io.Discard, "", 0)})
+
+	if _, err := svc.RequeueDeadLetters(context.Background(), DeadLetterFilter{}, false); !errors.Is(err, ErrValidation) {
+		t.Fatalf("err = %v, want a validation error", err)
+	}
+	if repo.calls != 0 {
+		t.Fatal("expected nothing to be requeued without all=true")
+	}
+
+	if _, err := svc.RequeueDeadLetters(context.Background(), DeadLetterFilter{}, true); err != nil {
+		t.Fatalf("requeue all: %v", err)
+	}
+	if _, err := svc.RequeueDeadLetters(context.Background(), DeadLetterFilter{To: "+905551112233"}, false); err != nil {
+		t.Fatalf("requeue filtered: %v", err)
+	}
+	if repo.calls != 2 {
+		t.Fatalf("requeued %d times, want 2", repo.calls)
+	}
+}
+
+type requeueRepo struct {
+	repository.MessageRepository
+	calls int
+}
+
+func (r *requeueRepo) RequeueDeadLetters(context.Context, repository.DeadLetterFilter) (int64, error) {
+	r.calls++
+	return 1, nil
+}
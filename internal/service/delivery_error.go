@@ -0,0 +1,98 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrorClass tells the retry logic how to treat a failed delivery.
+type ErrorClass string
+
+const (
+	// ErrorClassRetryable marks transient failures such as 5xx responses,
+	// timeouts and connection resets.
+	ErrorClassRetryable ErrorClass = "retryable"
+	// ErrorClassThrottled marks provider back-pressure (429, or 503 with
+	// Retry-After). Throttled attempts do not consume the retry budget.
+	ErrorClassThrottled ErrorClass = "throttled"
+	// ErrorClassPermanent marks failures that will not succeed on retry, such
+	// as 4xx validation errors or explicit rejections.
+	ErrorClassPermanent ErrorClass = "permanent"
+)
+
+// DeliveryError describes a failed webhook call.
+type DeliveryError struct {
+	Class      ErrorClass
+	StatusCode int
+	RetryAfter time.Duration
+	Err        error
+}
+
+func (e *DeliveryError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *DeliveryError) Unwrap() error {
+	return e.Err
+}
+
+func retryable(err error) *DeliveryError {
+	return &DeliveryError{Class: ErrorClassRetryable, Err: err}
+}
+
+func permanent(err error) *DeliveryError {
+	return &DeliveryError{Class: ErrorClassPermanent, Err: err}
+}
+
+// asDeliveryError classifies err. Errors that were not classified explicitly
+// are transport-level failures (timeouts, resets, refused connections) and
+// are treated as retryable.
+func asDeliveryError(err error) *DeliveryError {
+	var deliveryErr *DeliveryError
+	if errors.As(err, &deliveryErr) {
+		return deliveryErr
+	}
+	return retryable(err)
+}
+
+// classifyStatus turns a non-2xx webhook response into a DeliveryError.
+func classifyStatus(resp *http.Response, now time.Time) *DeliveryError {
+	err := &DeliveryError{
+		StatusCode: resp.StatusCode,
+		RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), now),
+		Err:        fmt.Errorf("webhook returned status %d", resp.StatusCode),
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests,
+		resp.StatusCode == http.StatusServiceUnavailable && err.RetryAfter > 0:
+		err.Class = ErrorClassThrottled
+	case resp.StatusCode == http.StatusRequestTimeout, resp.StatusCode >= 500:
+		err.Class = ErrorClassRetryable
+	default:
+		err.Class = ErrorClassPermanent
+	}
+	return err
+}
+
+// parseRetryAfter reads a Retry-After header given either in seconds or as an HTTP date.
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(value); err == nil && at.After(now) {
+		return at.Sub(now)
+	}
+	return 0
+}
@@ -0,0 +1,42 @@
+package service
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestClassifyStatus(t *testing.T) {
+	now := time.Date(2030, 1, 1, 9, 0, 0, 0, time.UTC)
+
+	cases := []struct {
+		status     int
+		retryAfter string
+		class      ErrorClass
+		wait       time.Duration
+	}{
+		{status: http.StatusTooManyRequests, retryAfter: "30", class: ErrorClassThrottled, wait: 30 * time.Second},
+		{status: http.StatusTooManyRequests, class: ErrorClassThrottled},
+		{status: http.StatusServiceUnavailable, retryAfter: now.Add(time.Minute).Format(http.TimeFormat), class: ErrorClassThrottled, wait: time.Minute},
+		{status: http.StatusServiceUnavailable, class: ErrorClassRetryable},
+		{status: http.StatusBadGateway, class: ErrorClassRetryable},
+		{status: http.StatusRequestTimeout, class: ErrorClassRetryable},
+		{status: http.StatusBadRequest, class: ErrorClassPermanent},
+		{status: http.StatusUnauthorized, class: ErrorClassPermanent},
+	}
+
+	for _, tc := range cases {
+		resp := &http.Response{StatusCode: tc.status, Header: http.Header{}}
+		if tc.retryAfter != "" {
+			resp.Header.Set("Retry-After", tc.retryAfter)
+		}
+
+		err := classifyStatus(resp, now)
+		if err.Class != tc.class {
+			t.Fatalf("status %d: expected class %s, got %s", tc.status, tc.class, err.Class)
+		}
+		if err.RetryAfter != tc.wait {
+			t.Fatalf("status %d: expected retry after %v, got %v", tc.status, tc.wait, err.RetryAfter)
+		}
+	}
+}
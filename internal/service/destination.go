@@ -0,0 +1,93 @@
+package service
+
+import (
+	"math/rand/v2"
+	"sort"
+
+	"automessaging/internal/circuitbreaker"
+)
+
+// Destination is a provider together with its routing attributes.
+type Destination struct {
+	Provider Provider
+	// Priority orders destinations; lower values are tried first, so cheaper
+	// gateways should get lower priorities.
+	Priority int
+	// Weight is the relative share of traffic within a priority tier. Values
+	// below one are treated as one.
+	Weight int
+}
+
+// destination is a Destination with its own circuit breaker.
+type destination struct {
+	provider Provider
+	priority int
+	weight   int
+	breaker  *circuitbreaker.Breaker
+}
+
+func newDestinations(configured []Destination, settings circuitbreaker.Settings) []*destination {
+	destinations := make([]*destination, 0, len(configured))
+	for _, d := range configured {
+		if d.Provider == nil {
+			continue
+		}
+		breakerSettings := settings
+		breakerSettings.Name = d.Provider.Name()
+		destinations = append(destinations, &destination{
+			provider: d.Provider,
+			priority: d.Priority,
+			weight:   max(d.Weight, 1),
+			breaker:  circuitbreaker.New(breakerSettings),
+		})
+	}
+	return destinations
+}
+
+func (d *destination) name() string {
+	return d.provider.Name()
+}
+
+// routeOrder returns destinations in the order they should be tried: by
+// priority, and within a tier in a weighted random order so traffic is split
+// by weight and a failure falls over to the tier's other members first.
+func routeOrder(destinations []*destination, random func(n int) int) []*destination {
+	ordered := make([]*destination, len(destinations))
+	copy(ordered, destinations)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return ordered[i].priority < ordered[j].priority
+	})
+
+	for start := 0; start < len(ordered); {
+		end := start + 1
+		for end < len(ordered) && ordered[end].priority == ordered[start].priority {
+			end++
+		}
+		weightedShuffle(ordered[start:end], random)
+		start = end
+	}
+	return ordered
+}
+
+// weightedShuffle reorders tier in place by repeatedly drawing an element with
+// probability proportional to its weight.
+func weightedShuffle(tier []*destination, random func(n int) int) {
+	for i := 0; i < len(tier)-1; i++ {
+		total := 0
+		for _, d := range tier[i:] {
+			total += d.weight
+		}
+		pick := random(total)
+		for j := i; j < len(tier); j++ {
+			pick -= tier[j].weight
+			if pick < 0 {
+				tier[i], tier[j] = tier[j], tier[i]
+				break
+			}
+		}
+	}
+}
+
+func randomIntN(n int) int {
+	return rand.IntN(n)
+}
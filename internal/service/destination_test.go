@@ -0,0 +1,115 @@
+package service
+
+import (
+	"context"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"automessaging/internal/model"
+	"automessaging/internal/repository"
+)
+
+func TestRouteOrder(t *testing.T) {
+	primary := &destination{provider: namedProvider("primary"), priority: 0, weight: 3}
+	secondary := &destination{provider: namedProvider("secondary"), priority: 0, weight: 1}
+	backup := &destination{provider: namedProvider("backup"), priority: 1, weight: 10}
+	destinations := []*destination{backup, secondary, primary}
+
+	// A draw below the first weight keeps the current element in place.
+	ordered := routeOrder(destinations, func(int) int { return 0 })
+	if names(ordered) != "secondary,primary,backup" {
+		t.Fatalf("unexpected order %s", names(ordered))
+	}
+
+	// Drawing the last ticket of the tier (weights 1+3) selects primary first.
+	ordered = routeOrder(destinations, func(n int) int { return n - 1 })
+	if names(ordered) != "primary,secondary,backup" {
+		t.Fatalf("unexpected order %s", names(ordered))
+	}
+}
+
+func TestDeliverFailsOverOnRetryableError(t *testing.T) {
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer failing.Close()
+	healthy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, `{"message":"Accepted","messageId":"remote-1"}`)
+	}))
+	defer healthy.Close()
+
+	repo := &journalRepo{}
+	svc := NewMessageService(Dependencies{
+		Repo: repo,
+		Destinations: []Destination{
+			{Provider: mustWebhookProvider(t, "primary", failing.URL), Priority: 0},
+			{Provider: mustWebhookProvider(t, "secondary", healthy.URL), Priority: 1},
+		},
+	}, MessageServiceOptions{Logger: log.New(io.Discard, "", 0)})
+
+	sent, err := svc.deliver(context.Background(), model.Message{To: "+905551111111", Content: "hi"}, svc.destinations)
+	if err != nil {
+		t.Fatalf("deliver: %v", err)
+	}
+	if sent.result.RemoteID != "remote-1" || sent.destination != "secondary" {
+		t.Fatalf("expected delivery through secondary, got %q from %s", sent.result.RemoteID, sent.destination)
+	}
+	if repo.begun != 2 || len(repo.finished) != 1 || repo.finished[0].State != model.AttemptFailed {
+		t.Fatalf("expected two journaled calls with the first failed, got %d begun, %+v", repo.begun, repo.finished)
+	}
+
+	snapshots := svc.CircuitBreakers()
+	if snapshots[0].Failures != 1 || snapshots[1].Failures != 0 {
+		t.Fatalf("unexpected breaker state: %+v", snapshots)
+	}
+}
+
+// journalRepo records attempt journal calls; other methods are not used.
+type journalRepo struct {
+	repository.MessageRepository
+	begun    int
+	finished []repository.AttemptOutcome
+}
+
+func (r *journalRepo) BeginAttempt(context.Context, uuid.UUID, string, string) (uuid.UUID, error) {
+	r.begun++
+	return uuid.New(), nil
+}
+
+func (r *journalRepo) FinishAttempt(_ context.Context, _ uuid.UUID, outcome repository.AttemptOutcome) error {
+	r.finished = append(r.finished, outcome)
+	return nil
+}
+
+type namedProvider string
+
+func (p namedProvider) Name() string { return string(p) }
+
+func (p namedProvider) Send(context.Context, model.Message) (SendResult, error) {
+	return SendResult{}, nil
+}
+
+func names(destinations []*destination) string {
+	out := ""
+	for i, d := range destinations {
+		if i > 0 {
+			out += ","
+		}
+		out += d.name()
+	}
+	return out
+}
+
+func mustWebhookProvider(t *testing.T, name, url string) *WebhookProvider {
+	t.Helper()
+	provider, err := NewWebhookProvider(WebhookProviderOptions{Name: name, URL: url, Mapping: DefaultWebhookMapping()})
+	if err != nil {
+		t.Fatalf("new provider: %v", err)
+	}
+	return provider
+}
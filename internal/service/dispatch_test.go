@@ -0,0 +1,145 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"automessaging/internal/circuitbreaker"
+	"automessaging/internal/model"
+	"automessaging/internal/repository"
+)
+
+func TestDispatchReleasesClaimsNotStartedBeforeDeadline(t *testing.T) {
+	repo := &dispatchRepo{statuses: make(map[uuid.UUID]model.MessageStatus)}
+	provider := &slowProvider{delay: 200 * time.Millisecond}
+	svc := NewMessageService(Dependencies{
+		Repo:         repo,
+		Destinations: []Destination{{Provider: provider}},
+	}, MessageServiceOptions{
+		MaxInFlight:      1,
+		IterationTimeout: 50 * time.Millisecond,
+		Logger:           log.New(io.Discard, "", 0),
+	})
+
+	messages := make([]model.Message, 3)
+	for i := range messages {
+		messages[i] = model.Message{ID: uuid.New(), To: "+905551112233", Content: "hello", Status: model.StatusProcessing}
+		repo.statuses[messages[i].ID] = model.StatusProcessing
+	}
+
+	start := time.Now()
+	svc.dispatch(context.Background(), messages)
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("dispatch took %v, expected it to stop waiting at the deadline", elapsed)
+	}
+
+	if calls := provider.calls.Load(); calls != 1 {
+		t.Fatalf("provider called %d times, want 1", calls)
+	}
+	if got := repo.status(messages[0].ID); got != model.StatusSent {
+		t.Errorf("started message is %s, want sent", got)
+	}
+	for _, msg := range messages[1:] {
+		if got := repo.status(msg.ID); got != model.StatusQueued {
+			t.Errorf("unstarted message %s is %s, want queued", msg.ID, got)
+		}
+	}
+}
+
+func TestSendMessageHoldsBackRouteWithoutAvailableDestination(t *testing.T) {
+	repo := &dispatchRepo{statuses: make(map[uuid.UUID]model.MessageStatus)}
+	svc := NewMessageService(Dependencies{
+		Repo:         repo,
+		Destinations: []Destination{{Provider: namedProvider("tr")}, {Provider: namedProvider("us")}},
+	}, MessageServiceOptions{
+		Routes:         []Route{{Prefix: "+90", Destinations: []string{"tr"}}},
+		CircuitBreaker: circuitbreaker.Settings{MinRequests: 1, CoolDown: time.Minute},
+		Logger:         log.New(io.Discard, "", 0),
+	})
+	before := time.Now()
+	for _, dest := range svc.destinations {
+		if dest.name() == "tr" {
+			_ = dest.breaker.Allow()
+			dest.breaker.Record(false)
+		}
+	}
+
+	msg := model.Message{ID: uuid.New(), To: "+905551112233", Content: "hello", Status: model.StatusProcessing}
+	if err := svc.sendMessage(context.Background(), msg); !errors.Is(err, errNotAttempted) {
+		t.Fatalf("sendMessage = %v, want %v", err, errNotAttempted)
+	}
+	if got := repo.status(msg.ID); got != model.StatusQueued {
+		t.Fatalf("message is %s, want queued", got)
+	}
+	retryAt := repo.retryAt[msg.ID]
+	if retryAt == nil || retryAt.Before(before.Add(time.Minute)) || retryAt.After(time.Now().Add(time.Minute)) {
+		t.Fatalf("released with retry at %v, want the end of the breaker cool-down", retryAt)
+	}
+}
+
+// slowProvider accepts every message after delay unless ctx ends first, like
+// an HTTP call built with the request context.
+type slowProvider struct {
+	delay time.Duration
+	calls atomic.Int32
+}
+
+func (p *slowProvider) Name() string { return "slow" }
+
+func (p *slowProvider) Send(ctx context.Context, _ model.Message) (SendResult, error) {
+	p.calls.Add(1)
+	select {
+	case <-time.After(p.delay):
+		return SendResult{}, nil
+	case <-ctx.Done():
+		return SendResult{}, ctx.Err()
+	}
+}
+
+// dispatchRepo tracks the status each message ends up in.
+type dispatchRepo struct {
+	repository.MessageRepository
+	mu       sync.Mutex
+	statuses map[uuid.UUID]model.MessageStatus
+	retryAt  map[uuid.UUID]*time.Time
+}
+
+func (r *dispatchRepo) status(id uuid.UUID) model.MessageStatus {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.statuses[id]
+}
+
+func (r *dispatchRepo) set(id uuid.UUID, status model.MessageStatus) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.statuses[id] = status
+}
+
+func (r *dispatchRepo) ReleaseClaim(_ context.Context, id uuid.UUID, _ string, retryAt *time.Time) error {
+	r.set(id, model.StatusQueued)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.retryAt == nil {
+		r.retryAt = make(map[uuid.UUID]*time.Time)
+	}
+	r.retryAt[id] = retryAt
+	return nil
+}
+
+func (r *dispatchRepo) BeginAttempt(context.Context, uuid.UUID, string, string) (uuid.UUID, error) {
+	return uuid.New(), nil
+}
+
+func (r *dispatchRepo) MarkAsSent(_ context.Context, id uuid.UUID, _ string, _ repository.DeliverySuccess) error {
+	r.set(id, model.StatusSent)
+	return nil
+}
@@ -0,0 +1,110 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"automessaging/internal/model"
+	"automessaging/internal/repository"
+)
+
+// ErrConflict is returned when a message is no longer in a state that allows
+// the requested change, e.g. the scheduler already claimed it.
+var ErrConflict = errors.New("message state conflict")
+
+// ErrVersionMismatch is returned when a conditional change names a version
+// the message has already moved past.
+var ErrVersionMismatch = errors.New("message version mismatch")
+
+// EditMessageInput changes a queued message. Omitted fields keep their
+// current value.
+type EditMessageInput struct {
+	To       *string    `json:"to,omitempty"`
+	Content  *string    `json:"content,omitempty"`
+	SendAt   *time.Time `json:"send_at,omitempty"`
+	Priority *int       `json:"priority,omitempty"`
+}
+
+// EditMessage applies input to a queued message that is still at version.
+// Every claim bumps the version, so an edit can never change a message the
+// scheduler is already sending. A new recipient gets the time zone of its
+// number unless the caller gave one explicitly. Moving send_at to or past the message's
+// expires_at is rejected.
+func (s *MessageService) EditMessage(ctx context.Context, id uuid.UUID, version int, input EditMessageInput) (model.Message, error) {
+	if input.To == nil && input.Content == nil && input.SendAt == nil && input.Priority == nil {
+		return model.Message{}, fmt.Errorf("%w: nothing to change", ErrValidation)
+	}
+
+	var edit repository.MessageEdit
+	if input.To != nil {
+		to, err := validRecipient(*input.To)
+		if err != nil {
+			return model.Message{}, err
+		}
+		zone := s.quietHours.infer(to)
+		edit.To, edit.RecipientTimezone = &to, &zone
+	}
+	if input.Content != nil {
+		if err := validateContent(*input.Content); err != nil {
+			return model.Message{}, err
+		}
+		edit.Content = input.Content
+	}
+	if input.SendAt != nil {
+		sendAt := input.SendAt.UTC()
+		edit.SendAt = &sendAt
+	}
+	if input.Priority != nil {
+		if err := validatePriority(*input.Priority); err != nil {
+			return model.Message{}, err
+		}
+		edit.Priority = input.Priority
+	}
+
+	msg, err := s.deps.repo.UpdateQueued(ctx, id, version, edit)
+	if !errors.Is(err, sql.ErrNoRows) {
+		return msg, err
+	}
+
+	current, err := s.GetMessage(ctx, id)
+	if err != nil {
+		return model.Message{}, err
+	}
+	if current.Status == model.StatusQueued && current.Version == version &&
+		edit.SendAt != nil && current.ExpiresAt != nil && !current.ExpiresAt.After(*edit.SendAt) {
+		return model.Message{}, fmt.Errorf("%w: send_at must be before the message's expires_at (%s)", ErrValidation, current.ExpiresAt.UTC().Format(time.RFC3339))
+	}
+	return model.Message{}, unchangedReason(current)
+}
+
+// CancelMessage cancels a queued message. A zero version cancels whatever
+// version is current. Cancelling a cancelled message returns it unchanged.
+func (s *MessageService) CancelMessage(ctx context.Context, id uuid.UUID, version int) (model.Message, error) {
+	msg, err := s.deps.repo.CancelQueued(ctx, id, version)
+	if !errors.Is(err, sql.ErrNoRows) {
+		return msg, err
+	}
+
+	current, err := s.GetMessage(ctx, id)
+	if err != nil {
+		return model.Message{}, err
+	}
+	if current.Status == model.StatusCancelled {
+		return current, nil
+	}
+	return model.Message{}, unchangedReason(current)
+}
+
+// unchangedReason explains why a conditional update of a queued message
+// matched no row, given the message as it is now.
+func unchangedReason(current model.Message) error {
+	if current.Status != model.StatusQueued {
+		return fmt.Errorf("%w: message is %s", ErrConflict, current.Status)
+	}
+	return fmt.Errorf("%w: message is at version %d", ErrVersionMismatch, current.Version)
+}
@@ -0,0 +1,80 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"io"
+	"log"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"automessaging/internal/model"
+	"automessaging/internal/repository"
+)
+
+func TestEditMessageExplainsRejectedEdits(t *testing.T) {
+	content := "fixed typo"
+	cases := []struct {
+		name    string
+		current model.Message
+		want    error
+	}{
+		{"claimed by the scheduler", model.Message{Status: model.StatusProcessing, Version: 2}, ErrConflict},
+		{"edited concurrently", model.Message{Status: model.StatusQueued, Version: 3}, ErrVersionMismatch},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			svc := NewMessageService(Dependencies{Repo: &staleRepo{current: tc.current}}, MessageServiceOptions{Logger: log.New(io.Discard, "", 0)})
+
+			_, err := svc.EditMessage(context.Background(), uuid.New(), 1, EditMessageInput{Content: &content})
+			if !errors.Is(err, tc.want) {
+				t.Fatalf("err = %v, want %v", err, tc.want)
+			}
+		})
+	}
+}
+
+func TestEditMessageRejectsSendAtPastExpiry(t *testing.T) {
+	expiresAt := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	repo := &staleRepo{current: model.Message{Status: model.StatusQueued, Version: 1, ExpiresAt: &expiresAt}}
+	svc := NewMessageService(Dependencies{Repo: repo}, MessageServiceOptions{Logger: log.New(io.Discard, "", 0)})
+
+	for _, sendAt := range []time.Time{expiresAt, expiresAt.Add(time.Hour)} {
+		_, err := svc.EditMessage(context.Background(), uuid.New(), 1, EditMessageInput{SendAt: &sendAt})
+		if !errors.Is(err, ErrValidation) {
+			t.Fatalf("send_at %v: err = %v, want ErrValidation", sendAt, err)
+		}
+	}
+}
+
+func TestCancelMessageIsIdempotent(t *testing.T) {
+	repo := &staleRepo{current: model.Message{Status: model.StatusCancelled, Version: 2}}
+	svc := NewMessageService(Dependencies{Repo: repo}, MessageServiceOptions{Logger: log.New(io.Discard, "", 0)})
+
+	msg, err := svc.CancelMessage(context.Background(), uuid.New(), 0)
+	if err != nil || msg.Status != model.StatusCancelled {
+		t.Fatalf("cancel = %v, %v", msg.Status, err)
+	}
+}
+
+// staleRepo matches no conditional update and reports current on lookup.
+type staleRepo struct {
+	repository.MessageRepository
+	current model.Message
+}
+
+func (r *staleRepo) UpdateQueued(context.Context, uuid.UUID, int, repository.MessageEdit) (model.Message, error) {
+	return model.Message{}, sql.ErrNoRows
+}
+
+func (r *staleRepo) CancelQueued(context.Context, uuid.UUID, int) (model.Message, error) {
+	return model.Message{}, sql.ErrNoRows
+}
+
+func (r *staleRepo) FindByID(context.Context, uuid.UUID) (model.Message, error) {
+	return r.current, nil
+}
@@ -0,0 +1,92 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"testing"
+	"time"
+
+	"automessaging/internal/model"
+	"automessaging/internal/repository"
+)
+
+func TestExpiryForCountsTTLFromDueTime(t *testing.T) {
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	sendAt := now.Add(time.Hour)
+	ttl := 300
+
+	expiresAt, err := expiryFor(CreateMessageInput{SendAt: &sendAt, TTLSeconds: &ttl}, now)
+	if err != nil || !expiresAt.Equal(sendAt.Add(5*time.Minute)) {
+		t.Fatalf("expiresAt = %v, %v", expiresAt, err)
+	}
+
+	past := now.Add(-time.Minute)
+	if _, err := expiryFor(CreateMessageInput{ExpiresAt: &past}, now); !errors.Is(err, ErrValidation) {
+		t.Fatalf("past expiry: err = %v, want ErrValidation", err)
+	}
+	if _, err := expiryFor(CreateMessageInput{ExpiresAt: &sendAt, TTLSeconds: &ttl}, now); !errors.Is(err, ErrValidation) {
+		t.Fatalf("both set: err = %v, want ErrValidation", err)
+	}
+}
+
+func TestFailureForExpiresInsteadOfRetryingLate(t *testing.T) {
+	svc := &MessageService{retryPolicy: RetryPolicy{BaseDelay: time.Minute, Multiplier: 1, MaxDelay: time.Minute, MaxAttempts: 5}}
+	attemptedAt := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	retryable := &DeliveryError{Class: ErrorClassRetryable, Err: errors.New("timeout")}
+
+	soon := attemptedAt.Add(30 * time.Second)
+	if got := svc.failureFor(model.Message{ExpiresAt: &soon}, attemptedAt, retryable); got.Status != model.StatusExpired {
+		t.Fatalf("status = %s, want expired", got.Status)
+	}
+
+	later := attemptedAt.Add(time.Hour)
+	if got := svc.failureFor(model.Message{ExpiresAt: &later}, attemptedAt, retryable); got.Status != model.StatusQueued {
+		t.Fatalf("status = %s, want queued", got.Status)
+	}
+}
+
+func TestIdempotentReplayWithDifferentExpiryConflicts(t *testing.T) {
+	svc := NewMessageService(Dependencies{Repo: &idempotentRepo{}}, MessageServiceOptions{Logger: log.New(io.Discard, "", 0)})
+	ctx := context.Background()
+	ttl, longer := 300, 600
+	expiresAt := time.Now().Add(time.Hour).UTC()
+	input := func(ttl *int, expiresAt *time.Time) CreateMessageInput {
+		return CreateMessageInput{To: "+905551112233", Content: "code 1234", TTLSeconds: ttl, ExpiresAt: expiresAt, IdempotencyKey: "otp-1"}
+	}
+
+	if _, created, err := svc.CreateMessage(ctx, input(&ttl, nil)); err != nil || !created {
+		t.Fatalf("first request: created %v, err %v", created, err)
+	}
+	if _, created, err := svc.CreateMessage(ctx, input(&ttl, nil)); err != nil || created {
+		t.Fatalf("replay: created %v, err %v; want the original message", created, err)
+	}
+	for name, replay := range map[string]CreateMessageInput{
+		"different ttl": input(&longer, nil),
+		"no ttl":        input(nil, nil),
+		"expires_at":    input(nil, &expiresAt),
+	} {
+		if _, _, err := svc.CreateMessage(ctx, replay); !errors.Is(err, ErrIdempotencyConflict) {
+			t.Errorf("%s: err = %v, want %v", name, err, ErrIdempotencyConflict)
+		}
+	}
+}
+
+// idempotentRepo keeps the first message stored under each idempotency key.
+type idempotentRepo struct {
+	repository.MessageRepository
+	stored map[string]model.Message
+}
+
+func (r *idempotentRepo) CreateIdempotent(_ context.Context, msg *model.Message) (bool, error) {
+	if existing, ok := r.stored[msg.IdempotencyKey]; ok {
+		*msg = existing
+		return false, nil
+	}
+	if r.stored == nil {
+		r.stored = make(map[string]model.Message)
+	}
+	r.stored[msg.IdempotencyKey] = *msg
+	return true, nil
+}
@@ -0,0 +1,147 @@
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"automessaging/internal/model"
+	"automessaging/internal/repository"
+)
+
+// MessageListQuery filters and pages a message listing. Cursor continues from
+// a previous page's next_cursor and takes precedence over Page. Offset pages
+// always report Total as they did before cursors existed; cursor pages only
+// count it when IncludeTotal is set because it scans every matching row.
+type MessageListQuery struct {
+	Statuses        []model.MessageStatus
+	To              string
+	ContentContains string
+	From            *time.Time
+	Until           *time.Time
+	Cursor          string
+	Page            int
+	Limit           int
+	IncludeTotal    bool
+}
+
+// MessageListResult captures a page of a message listing. NextCursor is empty
+// on the last page; Page is only reported for offset pagination.
+type MessageListResult struct {
+	Messages   []model.Message `json:"messages"`
+	Total      *int            `json:"total,omitempty"`
+	Page       int             `json:"page,omitempty"`
+	Limit      int             `json:"limit"`
+	NextCursor string          `json:"next_cursor,omitempty"`
+}
+
+// ListSentMessages returns sent, delivered and undelivered messages, most
+// recently sent first.
+func (s *MessageService) ListSentMessages(ctx context.Context, query MessageListQuery) (MessageListResult, error) {
+	return s.listMessages(ctx, repository.ViewSent, query)
+}
+
+// ListPendingMessages returns queued and processing messages, oldest first.
+func (s *MessageService) ListPendingMessages(ctx context.Context, query MessageListQuery) (MessageListResult, error) {
+	return s.listMessages(ctx, repository.ViewPending, query)
+}
+
+// ListFailedMessages returns failed messages, most recently attempted first.
+func (s *MessageService) ListFailedMessages(ctx context.Context, query MessageListQuery) (MessageListResult, error) {
+	return s.listMessages(ctx, repository.ViewFailed, query)
+}
+
+func (s *MessageService) listMessages(ctx context.Context, view repository.MessageView, query MessageListQuery) (MessageListResult, error) {
+	for _, status := range query.Statuses {
+		if !slices.Contains(view.Statuses(), status) {
+			return MessageListResult{}, fmt.Errorf("%w: status %q is not listed under %s messages", ErrValidation, status, view)
+		}
+	}
+	if query.From != nil && query.Until != nil && !query.From.Before(*query.Until) {
+		return MessageListResult{}, fmt.Errorf("%w: from must be before until", ErrValidation)
+	}
+
+	page, limit := normalizePage(query.Page, query.Limit)
+	countTotal := query.IncludeTotal || query.Cursor == ""
+	repoQuery := repository.MessageQuery{
+		View:            view,
+		Statuses:        query.Statuses,
+		To:              query.To,
+		ContentContains: query.ContentContains,
+		From:            query.From,
+		Until:           query.Until,
+		Offset:          (page - 1) * limit,
+		// One extra row tells whether another page follows.
+		Limit:      limit + 1,
+		CountTotal: countTotal,
+	}
+	if query.Cursor != "" {
+		after, err := decodeCursor(view, query.Cursor)
+		if err != nil {
+			return MessageListResult{}, err
+		}
+		repoQuery.After = &after
+		page = 0
+	}
+
+	items, total, err := s.deps.repo.ListMessages(ctx, repoQuery)
+	if err != nil {
+		return MessageListResult{}, err
+	}
+
+	result := MessageListResult{Messages: items, Page: page, Limit: limit}
+	if len(items) > limit {
+		result.Messages = items[:limit]
+		result.NextCursor = encodeCursor(view, result.Messages[limit-1])
+	}
+	if countTotal {
+		result.Total = &total
+	}
+	return result, nil
+}
+
+// encodeCursor captures the view's ordering key of msg as an opaque token.
+// The view is part of the token because each view orders by a different
+// timestamp, so a cursor is meaningless in any other listing.
+func encodeCursor(view repository.MessageView, msg model.Message) string {
+	at := msg.CreatedAt
+	switch {
+	case view == repository.ViewSent && msg.SentAt != nil:
+		at = *msg.SentAt
+	case view == repository.ViewFailed && msg.LastAttemptAt != nil:
+		at = *msg.LastAttemptAt
+	}
+	raw := string(view) + "|" + at.UTC().Format(time.RFC3339Nano) + "|" + msg.ID.String()
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeCursor(view repository.MessageView, token string) (repository.Cursor, error) {
+	invalid := fmt.Errorf("%w: invalid cursor", ErrValidation)
+
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return repository.Cursor{}, invalid
+	}
+	parts := strings.Split(string(raw), "|")
+	if len(parts) != 3 {
+		return repository.Cursor{}, invalid
+	}
+	rawView, rawAt, rawID := parts[0], parts[1], parts[2]
+	if rawView != string(view) {
+		return repository.Cursor{}, fmt.Errorf("%w: cursor belongs to the %s listing, not %s", ErrValidation, rawView, view)
+	}
+	at, err := time.Parse(time.RFC3339Nano, rawAt)
+	if err != nil {
+		return repository.Cursor{}, invalid
+	}
+	id, err := uuid.Parse(rawID)
+	if err != nil {
+		return repository.Cursor{}, invalid
+	}
+	return repository.Cursor{At: at, ID: id}, nil
+}
@@ -0,0 +1,80 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"automessaging/internal/model"
+	"automessaging/internal/repository"
+)
+
+func TestListMessagesPagesByCursor(t *testing.T) {
+	sentAt := time.Date(2025, 3, 1, 12, 0, 0, 123456000, time.UTC)
+	repo := &listingRepo{items: []model.Message{
+		{ID: uuid.New(), SentAt: &sentAt},
+		{ID: uuid.New(), SentAt: &sentAt},
+		{ID: uuid.New(), SentAt: &sentAt},
+	}}
+	svc := NewMessageService(Dependencies{Repo: repo}, MessageServiceOptions{Logger: log.New(io.Discard, "", 0)})
+
+	first, err := svc.ListSentMessages(context.Background(), MessageListQuery{Limit: 2})
+	if err != nil {
+		t.Fatalf("first page: %v", err)
+	}
+	if len(first.Messages) != 2 || first.NextCursor == "" || first.Total == nil || *first.Total != 3 {
+		t.Fatalf("first page = %d messages, cursor %q, total %v", len(first.Messages), first.NextCursor, first.Total)
+	}
+
+	repo.items = repo.items[2:]
+	second, err := svc.ListSentMessages(context.Background(), MessageListQuery{Limit: 2, Cursor: first.NextCursor})
+	if err != nil {
+		t.Fatalf("second page: %v", err)
+	}
+	if second.NextCursor != "" || second.Page != 0 || second.Total != nil {
+		t.Fatalf("second page cursor = %q, page = %d, total %v", second.NextCursor, second.Page, second.Total)
+	}
+	if after := repo.last.After; after == nil || after.ID != first.Messages[1].ID || !after.At.Equal(sentAt) {
+		t.Fatalf("cursor decoded to %+v", after)
+	}
+
+	_, err = svc.ListFailedMessages(context.Background(), MessageListQuery{Limit: 2, Cursor: first.NextCursor})
+	if !errors.Is(err, ErrValidation) {
+		t.Fatalf("sent cursor on failed listing: err = %v, want ErrValidation", err)
+	}
+}
+
+func TestListMessagesRejectsForeignStatus(t *testing.T) {
+	svc := NewMessageService(Dependencies{Repo: &listingRepo{}}, MessageServiceOptions{Logger: log.New(io.Discard, "", 0)})
+
+	_, err := svc.ListPendingMessages(context.Background(), MessageListQuery{Statuses: []model.MessageStatus{model.StatusSent}})
+	if !errors.Is(err, ErrValidation) {
+		t.Fatalf("err = %v, want ErrValidation", err)
+	}
+
+	_, err = svc.ListSentMessages(context.Background(), MessageListQuery{Cursor: "not-a-cursor"})
+	if !errors.Is(err, ErrValidation) {
+		t.Fatalf("err = %v, want ErrValidation", err)
+	}
+}
+
+// listingRepo serves items as a listing and records the last query.
+type listingRepo struct {
+	repository.MessageRepository
+	items []model.Message
+	last  repository.MessageQuery
+}
+
+func (r *listingRepo) ListMessages(_ context.Context, query repository.MessageQuery) ([]model.Message, int, error) {
+	r.last = query
+	items := r.items
+	if len(items) > query.Limit {
+		items = items[:query.Limit]
+	}
+	return items, len(r.items), nil
+}
@@ -0,0 +1,86 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+
+	"automessaging/internal/model"
+)
+
+// GetMessage returns the message with the given local id.
+func (s *MessageService) GetMessage(ctx context.Context, id uuid.UUID) (model.Message, error) {
+	msg, err := s.deps.repo.FindByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return model.Message{}, fmt.Errorf("%w: no message with id %s", ErrNotFound, id)
+		}
+		return model.Message{}, err
+	}
+	return msg, nil
+}
+
+// GetMessageByRemoteID returns the message a provider accepted under
+// remoteID, resolving the id through the Redis cache before Postgres.
+func (s *MessageService) GetMessageByRemoteID(ctx context.Context, remoteID string) (model.Message, error) {
+	if id, ok := s.cachedLocalID(ctx, remoteID); ok {
+		msg, err := s.GetMessage(ctx, id)
+		if !errors.Is(err, ErrNotFound) {
+			return msg, err
+		}
+	}
+
+	return s.findByRemoteID(ctx, remoteID)
+}
+
+// localIDForRemote resolves a provider message id using the Redis mapping
+// written on acceptance, falling back to Postgres when the cache misses or
+// is unavailable.
+func (s *MessageService) localIDForRemote(ctx context.Context, remoteID string) (uuid.UUID, error) {
+	if id, ok := s.cachedLocalID(ctx, remoteID); ok {
+		return id, nil
+	}
+
+	msg, err := s.findByRemoteID(ctx, remoteID)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	return msg.ID, nil
+}
+
+// findByRemoteID reads the mapping from Postgres after a cache miss and
+// writes it back to the cache.
+func (s *MessageService) findByRemoteID(ctx context.Context, remoteID string) (model.Message, error) {
+	msg, err := s.deps.repo.FindByRemoteID(ctx, remoteID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return model.Message{}, fmt.Errorf("%w: no message with remote id %s", ErrNotFound, remoteID)
+		}
+		return model.Message{}, err
+	}
+	s.cacheSentMetadata(ctx, msg)
+	return msg, nil
+}
+
+// cachedLocalID reads the local id stored under remoteID in Redis. Misses and
+// Redis failures both report false; failures are logged.
+func (s *MessageService) cachedLocalID(ctx context.Context, remoteID string) (uuid.UUID, bool) {
+	localID, err := s.deps.redis.HGet(ctx, sentMetadataKey(remoteID), "local_id").Result()
+	if err != nil {
+		if !errors.Is(err, redis.Nil) {
+			s.logger.Printf("failed to read metadata from redis for %s: %v", remoteID, err)
+		}
+		return uuid.Nil, false
+	}
+
+	id, err := uuid.Parse(localID)
+	if err != nil {
+		s.logger.Printf("ignoring malformed local_id %q cached for %s", localID, remoteID)
+		return uuid.Nil, false
+	}
+	return id, true
+}
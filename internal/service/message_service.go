@@ -1,98 +1,237 @@
 package service
 
 import (
-	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"log"
-	"net/http"
+	"math"
 	"os"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
+	"unicode/utf8"
 
 	"github.com/google/uuid"
 	"github.com/redis/go-redis/v9"
 
+	"automessaging/internal/circuitbreaker"
+	"automessaging/internal/metrics"
 	"automessaging/internal/model"
 	"automessaging/internal/repository"
 )
 
+const (
+	maxRecipientLength = 32
+	maxContentLength   = 160
+	maxBatchSize       = 500
+	maxIdempotencyKey  = 255
+)
+
+// ErrValidation is returned when submitted message input is invalid.
+var ErrValidation = errors.New("validation failed")
+
+// errNotAttempted marks deliveries that ended before any destination was called.
+var errNotAttempted = errors.New("delivery not attempted")
+
+// ErrIdempotencyConflict is returned when an idempotency key is reused with a different payload.
+var ErrIdempotencyConflict = errors.New("idempotency key already used with a different request")
+
 // MessageService orchestrates message processing.
 type MessageService struct {
 	deps           dependencies
-	client         *http.Client
-	webhookURL     string
-	webhookAuthKey string
+	destinations   []*destination
+	router         *router
 	fetchLimit     int
+	oldestFirst    int
+	leaseOwner     string
+	leaseDuration  time.Duration
+	retryPolicy    RetryPolicy
+	maxInFlight    int
+	iterationLimit time.Duration
+	metadataTTL    time.Duration
+	quietHours     *quietHours
 	logger         *log.Logger
 }
 
 type dependencies struct {
-	repo  repository.MessageRepository
-	redis redis.Cmdable
+	repo    repository.MessageRepository
+	redis   redis.Cmdable
+	limiter RateLimiter
+}
+
+// RateLimiter throttles outbound webhook calls per destination and honors
+// provider-requested pauses.
+type RateLimiter interface {
+	Wait(ctx context.Context, destination string) error
+	Pause(ctx context.Context, destination string, d time.Duration) error
+	PausedFor(ctx context.Context, destination string) (time.Duration, error)
 }
 
 // MessageServiceOptions configures MessageService.
 type MessageServiceOptions struct {
-	FetchLimit     int
-	WebhookURL     string
-	WebhookAuthKey string
-	HTTPTimeout    time.Duration
-	Logger         *log.Logger
+	FetchLimit    int
+	LeaseOwner    string
+	LeaseDuration time.Duration
+	RetryPolicy   RetryPolicy
+	// LowPriorityShare reserves this fraction of every claimed batch, rounded
+	// up, for the longest-waiting messages regardless of priority. At least
+	// one slot always follows priority order.
+	LowPriorityShare float64
+	// MaxInFlight bounds concurrent provider calls within one iteration.
+	MaxInFlight int
+	// IterationTimeout bounds how long one iteration may spend starting
+	// provider calls; calls already started run to completion, bounded by
+	// the provider's own timeout. Zero disables the deadline.
+	IterationTimeout time.Duration
+	// Routes restricts recipients with a matching prefix to a subset of the
+	// destinations.
+	Routes []Route
+	// CircuitBreaker configures the breaker wrapped around each destination;
+	// the name is taken from the destination's provider.
+	CircuitBreaker circuitbreaker.Settings
+	// QuietHours, when set, defers non-transactional messages while the
+	// recipient's local time is inside the window.
+	QuietHours *QuietHours
+	// SentMetadataTTL expires the Redis remote-id cache entries; zero keeps
+	// them forever.
+	SentMetadataTTL time.Duration
+	Logger          *log.Logger
 }
 
-// SentMessagesResult captures paginated sent messages.
-type SentMessagesResult struct {
-	Messages []model.Message `json:"messages"`
-	Total    int             `json:"total"`
-	Page     int             `json:"page"`
-	Limit    int             `json:"limit"`
+// CreateMessageInput describes a message submitted for delivery. SendAt is
+// optional; when omitted the message is due immediately. Priority defaults to
+// model.DefaultPriority. ExpiresAt or TTLSeconds, counted from when the
+// message is due, stop it from being sent late. RecipientTimezone overrides
+// the zone inferred from the number for quiet hours, and Transactional
+// messages ignore quiet hours. IdempotencyKey is supplied by the caller out of
+// band (e.g. the Idempotency-Key header).
+type CreateMessageInput struct {
+	To                string     `json:"to"`
+	Content           string     `json:"content"`
+	SendAt            *time.Time `json:"send_at,omitempty"`
+	Priority          *int       `json:"priority,omitempty"`
+	ExpiresAt         *time.Time `json:"expires_at,omitempty"`
+	TTLSeconds        *int       `json:"ttl_seconds,omitempty"`
+	RecipientTimezone string     `json:"recipient_timezone,omitempty"`
+	Transactional     bool       `json:"transactional,omitempty"`
+	IdempotencyKey    string     `json:"-"`
 }
 
-// Dependencies groups constructor requirements for MessageService.
+// BatchItemResult reports the outcome of a single item in a batch submission.
+type BatchItemResult struct {
+	Index int        `json:"index"`
+	ID    *uuid.UUID `json:"id,omitempty"`
+	Error string     `json:"error,omitempty"`
+}
+
+// BatchCreateResult captures the outcome of a batch submission.
+type BatchCreateResult struct {
+	Items   []BatchItemResult `json:"items"`
+	Created int               `json:"created"`
+	Failed  int               `json:"failed"`
+}
+
+// Dependencies groups constructor requirements for MessageService. Limiter is
+// optional; without it provider calls are not throttled. Destinations lists
+// the providers messages are routed to; see Destination for how one is chosen.
 type Dependencies struct {
-	Repo  repository.MessageRepository
-	Redis redis.Cmdable
+	Repo         repository.MessageRepository
+	Redis        redis.Cmdable
+	Limiter      RateLimiter
+	Destinations []Destination
 }
 
 // NewMessageService builds a MessageService.
 func NewMessageService(deps Dependencies, opts MessageServiceOptions) *MessageService {
-	timeout := opts.HTTPTimeout
-	if timeout == 0 {
-		timeout = 15 * time.Second
-	}
-
 	fetchLimit := opts.FetchLimit
 	if fetchLimit <= 0 {
 		fetchLimit = 2
 	}
 
+	var oldestFirst int
+	if opts.LowPriorityShare > 0 {
+		oldestFirst = min(int(math.Ceil(opts.LowPriorityShare*float64(fetchLimit))), fetchLimit-1)
+	}
+
+	leaseOwner := opts.LeaseOwner
+	if leaseOwner == "" {
+		leaseOwner = defaultLeaseOwner()
+	}
+
+	leaseDuration := opts.LeaseDuration
+	if leaseDuration <= 0 {
+		leaseDuration = 5 * time.Minute
+	}
+
+	maxInFlight := opts.MaxInFlight
+	if maxInFlight <= 0 {
+		maxInFlight = 1
+	}
+
 	logger := opts.Logger
 	if logger == nil {
 		logger = log.New(os.Stdout, "message-service ", log.LstdFlags)
 	}
 
+	destinations := newDestinations(deps.Destinations, opts.CircuitBreaker)
+
 	return &MessageService{
 		deps: dependencies{
-			repo:  deps.Repo,
-			redis: deps.Redis,
+			repo:    deps.Repo,
+			redis:   deps.Redis,
+			limiter: deps.Limiter,
 		},
-		client:         &http.Client{Timeout: timeout},
-		webhookURL:     opts.WebhookURL,
-		webhookAuthKey: opts.WebhookAuthKey,
+		destinations:   destinations,
+		router:         newRouter(opts.Routes, destinations, logger),
 		fetchLimit:     fetchLimit,
+		oldestFirst:    oldestFirst,
+		leaseOwner:     leaseOwner,
+		leaseDuration:  leaseDuration,
+		retryPolicy:    opts.RetryPolicy.withDefaults(),
+		maxInFlight:    maxInFlight,
+		iterationLimit: opts.IterationTimeout,
+		metadataTTL:    opts.SentMetadataTTL,
+		quietHours:     newQuietHours(opts.QuietHours),
 		logger:         logger,
 	}
 }
 
-// ProcessPendingMessages fetches unsent messages and sends them to the webhook.
+// ProcessPendingMessages claims due messages and sends them through the
+// configured destinations using up to MaxInFlight concurrent requests. The
+// outcome of every attempt is persisted on the message row.
 func (s *MessageService) ProcessPendingMessages(ctx context.Context) error {
-	if s.webhookURL == "" {
-		return errors.New("webhook URL is not configured")
+	if len(s.destinations) == 0 {
+		return errors.New("no delivery destinations are configured")
+	}
+
+	if err := s.ReapExpiredLeases(ctx); err != nil {
+		s.logger.Printf("failed to reap expired leases: %v", err)
+	}
+
+	if err := s.ExpireStale(ctx); err != nil {
+		s.logger.Printf("failed to expire stale messages: %v", err)
 	}
 
-	messages, err := s.deps.repo.FetchNextUnsent(ctx, s.fetchLimit)
+	if !s.anyAvailable(ctx) {
+		s.logger.Printf("all destinations have an open circuit breaker or are paused, skipping iteration")
+		return nil
+	}
+
+	req := repository.ClaimRequest{
+		Owner:       s.leaseOwner,
+		Lease:       s.leaseDuration,
+		Limit:       s.fetchLimit,
+		OldestFirst: s.oldestFirst,
+	}
+	if s.quietHours != nil {
+		req.QuietHours = &s.quietHours.window
+	}
+	messages, err := s.deps.repo.ClaimNextUnsent(ctx, req)
 	if err != nil {
 		return err
 	}
@@ -101,17 +240,307 @@ func (s *MessageService) ProcessPendingMessages(ctx context.Context) error {
 		return nil
 	}
 
+	s.dispatch(ctx, messages)
+	return nil
+}
+
+// dispatch sends messages through a bounded worker pool. Messages that could
+// not be started before the iteration deadline are released without counting
+// an attempt; the deadline never aborts a call already made.
+func (s *MessageService) dispatch(ctx context.Context, messages []model.Message) {
+	if s.iterationLimit > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.iterationLimit)
+		defer cancel()
+	}
+
+	slots := make(chan struct{}, s.maxInFlight)
+	var wg sync.WaitGroup
 	for _, msg := range messages {
-		if err := s.sendMessage(ctx, msg); err != nil {
-			s.logger.Printf("failed to send message %s: %v", msg.ID, err)
+		if ctx.Err() != nil {
+			s.releaseClaim(ctx, msg, nil)
+			continue
+		}
+
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+			s.releaseClaim(ctx, msg, nil)
+			continue
+		}
+
+		wg.Go(func() {
+			defer func() { <-slots }()
+			if err := s.sendMessage(ctx, msg); err != nil {
+				s.logger.Printf("failed to send message %s: %v", msg.ID, err)
+			}
+		})
+	}
+	wg.Wait()
+}
+
+// waitForCapacity blocks until the rate limiter admits a call to destination.
+// Limiter failures are logged and the call is allowed so a Redis outage does
+// not stop delivery.
+func (s *MessageService) waitForCapacity(ctx context.Context, destination string) error {
+	if s.deps.limiter == nil {
+		return nil
+	}
+
+	err := s.deps.limiter.Wait(ctx, destination)
+	if err != nil && ctx.Err() == nil {
+		s.logger.Printf("rate limiter unavailable, proceeding without it: %v", err)
+		return nil
+	}
+	return err
+}
+
+// available reports whether dest may be tried: its breaker is not open and the
+// provider has not asked us to back off.
+func (s *MessageService) available(ctx context.Context, dest *destination) bool {
+	return dest.breaker.State() != circuitbreaker.StateOpen && s.pausedFor(ctx, dest.name()) == 0
+}
+
+// availableAt reports when the first of candidates can be tried again: when
+// its pause and breaker cool-down have both ended. It returns nil if one can
+// be tried now or none reports when it will recover.
+func (s *MessageService) availableAt(ctx context.Context, candidates []*destination) *time.Time {
+	now := time.Now().UTC()
+	var earliest *time.Time
+	for _, dest := range candidates {
+		at := now
+		if until := dest.breaker.Snapshot().OpenUntil; until != nil && until.After(at) {
+			at = until.UTC()
 		}
+		if paused := s.pausedFor(ctx, dest.name()); now.Add(paused).After(at) {
+			at = now.Add(paused)
+		}
+		if !at.After(now) {
+			return nil
+		}
+		if earliest == nil || at.Before(*earliest) {
+			earliest = &at
+		}
+	}
+	return earliest
+}
+
+func (s *MessageService) anyAvailable(ctx context.Context) bool {
+	for _, dest := range s.destinations {
+		if s.available(ctx, dest) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *MessageService) pausedFor(ctx context.Context, destination string) time.Duration {
+	if s.deps.limiter == nil {
+		return 0
+	}
+	paused, err := s.deps.limiter.PausedFor(ctx, destination)
+	if err != nil {
+		s.logger.Printf("failed to read pause for %s: %v", destination, err)
+		return 0
+	}
+	return paused
+}
+
+func (s *MessageService) pauseDestination(ctx context.Context, destination string, d time.Duration) {
+	if s.deps.limiter == nil || d <= 0 {
+		return
+	}
+	if err := s.deps.limiter.Pause(ctx, destination, d); err != nil {
+		s.logger.Printf("failed to pause %s: %v", destination, err)
+		return
+	}
+	s.logger.Printf("provider asked to back off, pausing %s for %s", destination, d)
+}
+
+func (s *MessageService) releaseClaim(ctx context.Context, msg model.Message, retryAt *time.Time) {
+	if err := s.deps.repo.ReleaseClaim(context.WithoutCancel(ctx), msg.ID, s.leaseOwner, retryAt); err != nil {
+		s.logger.Printf("failed to release claim for %s: %v", msg.ID, err)
+	}
+}
+
+// CircuitBreakers reports the state of the breakers guarding each destination.
+func (s *MessageService) CircuitBreakers() []circuitbreaker.Snapshot {
+	snapshots := make([]circuitbreaker.Snapshot, 0, len(s.destinations))
+	for _, dest := range s.destinations {
+		snapshots = append(snapshots, dest.breaker.Snapshot())
+	}
+	return snapshots
+}
+
+// ReapExpiredLeases resolves messages abandoned by crashed or stalled
+// instances. The attempt journal decides whether each one was already
+// accepted, may have been delivered, or can safely be queued again.
+func (s *MessageService) ReapExpiredLeases(ctx context.Context) error {
+	reaped, err := s.deps.repo.ReapExpiredLeases(ctx)
+	if err != nil {
+		return err
+	}
+	metrics.MessagesSent.Add(reaped.Reconciled)
+	metrics.MessagesFailed.Add(reaped.Unknown)
+	if reaped.Reconciled > 0 {
+		s.logger.Printf("marked %d messages with expired leases as sent from the attempt journal", reaped.Reconciled)
+	}
+	if reaped.Unknown > 0 {
+		s.logger.Printf("failed %d messages with expired leases whose delivery outcome is unknown", reaped.Unknown)
+	}
+	if reaped.Requeued > 0 {
+		s.logger.Printf("requeued %d messages with expired leases", reaped.Requeued)
 	}
+	return nil
+}
 
+// ExpireStale moves queued messages past their expiry to expired so they are
+// never sent late.
+func (s *MessageService) ExpireStale(ctx context.Context) error {
+	expired, err := s.deps.repo.ExpireStale(ctx)
+	if err != nil {
+		return err
+	}
+	if expired > 0 {
+		metrics.MessagesExpired.Add(expired)
+		s.logger.Printf("expired %d messages that passed their expires_at", expired)
+	}
 	return nil
 }
 
-// ListSentMessages returns paginated sent messages.
-func (s *MessageService) ListSentMessages(ctx context.Context, page, limit int) (SentMessagesResult, error) {
+// CreateMessage validates and enqueues a single message. When an idempotency
+// key is given and was seen before, the original message is returned and
+// created is false.
+func (s *MessageService) CreateMessage(ctx context.Context, input CreateMessageInput) (msg model.Message, created bool, err error) {
+	msg, err = newMessage(input)
+	if err != nil {
+		return model.Message{}, false, err
+	}
+
+	if input.IdempotencyKey == "" {
+		s.localize(&msg)
+		if err := s.deps.repo.Create(ctx, &msg); err != nil {
+			return model.Message{}, false, err
+		}
+		return msg, true, nil
+	}
+
+	if len(input.IdempotencyKey) > maxIdempotencyKey {
+		return model.Message{}, false, fmt.Errorf("%w: idempotency key must be at most %d characters", ErrValidation, maxIdempotencyKey)
+	}
+
+	msg.IdempotencyKey = input.IdempotencyKey
+	msg.IdempotencyFingerprint = fingerprint(msg, input.ExpiresAt, input.TTLSeconds)
+	wanted := msg.IdempotencyFingerprint
+	// Inferred after fingerprinting so a change to the prefix map does not
+	// turn a replay into a conflict.
+	s.localize(&msg)
+
+	created, err = s.deps.repo.CreateIdempotent(ctx, &msg)
+	if err != nil {
+		return model.Message{}, false, err
+	}
+	if !created && msg.IdempotencyFingerprint != wanted {
+		return model.Message{}, false, ErrIdempotencyConflict
+	}
+	return msg, created, nil
+}
+
+// CreateMessages validates and enqueues a batch of messages. Invalid items are
+// reported individually while the valid ones are stored together. When an
+// idempotency key is given and was seen before with the same batch, the
+// original result is returned and created is false; a different batch under
+// the same key is rejected with ErrIdempotencyConflict. A batch with no valid
+// item does not use up its key.
+func (s *MessageService) CreateMessages(ctx context.Context, inputs []CreateMessageInput, idempotencyKey string) (result BatchCreateResult, created bool, err error) {
+	if len(inputs) == 0 {
+		return BatchCreateResult{}, false, fmt.Errorf("%w: at least one message is required", ErrValidation)
+	}
+	if len(inputs) > maxBatchSize {
+		return BatchCreateResult{}, false, fmt.Errorf("%w: batch size must not exceed %d", ErrValidation, maxBatchSize)
+	}
+	if len(idempotencyKey) > maxIdempotencyKey {
+		return BatchCreateResult{}, false, fmt.Errorf("%w: idempotency key must be at most %d characters", ErrValidation, maxIdempotencyKey)
+	}
+
+	result = BatchCreateResult{Items: make([]BatchItemResult, len(inputs))}
+	valid := make([]model.Message, 0, len(inputs))
+	validIndexes := make([]int, 0, len(inputs))
+	for i, input := range inputs {
+		result.Items[i].Index = i
+		msg, err := newMessage(input)
+		if err != nil {
+			result.Items[i].Error = err.Error()
+			result.Failed++
+			continue
+		}
+		s.localize(&msg)
+		valid = append(valid, msg)
+		validIndexes = append(validIndexes, i)
+	}
+
+	if len(valid) == 0 {
+		return result, false, nil
+	}
+
+	if idempotencyKey != "" {
+		return s.createBatchIdempotent(ctx, inputs, idempotencyKey, valid, validIndexes, result)
+	}
+
+	stored, err := s.deps.repo.CreateBatch(ctx, valid)
+	if err != nil {
+		return BatchCreateResult{}, false, err
+	}
+
+	for i, msg := range stored {
+		id := msg.ID
+		result.Items[validIndexes[i]].ID = &id
+		result.Created++
+	}
+
+	return result, true, nil
+}
+
+// createBatchIdempotent stores valid together with the result it will
+// produce, so a replay returns exactly what the first request did. Ids are
+// assigned up front because the result is written in the same transaction as
+// the messages.
+func (s *MessageService) createBatchIdempotent(ctx context.Context, inputs []CreateMessageInput, key string, valid []model.Message, validIndexes []int, result BatchCreateResult) (BatchCreateResult, bool, error) {
+	for i := range valid {
+		id := uuid.New()
+		valid[i].ID = id
+		result.Items[validIndexes[i]].ID = &id
+		result.Created++
+	}
+
+	body, err := json.Marshal(result)
+	if err != nil {
+		return BatchCreateResult{}, false, err
+	}
+	wanted, err := batchFingerprint(inputs)
+	if err != nil {
+		return BatchCreateResult{}, false, err
+	}
+
+	stored, created, err := s.deps.repo.CreateBatchIdempotent(ctx, valid, repository.IdempotentBatch{Key: key, Fingerprint: wanted, Result: body})
+	if err != nil {
+		return BatchCreateResult{}, false, err
+	}
+	if created {
+		return result, true, nil
+	}
+	if stored.Fingerprint != wanted {
+		return BatchCreateResult{}, false, ErrIdempotencyConflict
+	}
+
+	var replayed BatchCreateResult
+	if err := json.Unmarshal(stored.Result, &replayed); err != nil {
+		return BatchCreateResult{}, false, err
+	}
+	return replayed, false, nil
+}
+
+func normalizePage(page, limit int) (int, int) {
 	if page <= 0 {
 		page = 1
 	}
@@ -121,87 +550,315 @@ func (s *MessageService) ListSentMessages(ctx context.Context, page, limit int)
 	if limit > 100 {
 		limit = 100
 	}
+	return page, limit
+}
 
-	offset := (page - 1) * limit
-	items, total, err := s.deps.repo.ListSent(ctx, offset, limit)
-	if err != nil {
-		return SentMessagesResult{}, err
+func defaultLeaseOwner() string {
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		host = "unknown"
 	}
-
-	return SentMessagesResult{
-		Messages: items,
-		Total:    total,
-		Page:     page,
-		Limit:    limit,
-	}, nil
+	return fmt.Sprintf("%s-%d", host, os.Getpid())
 }
 
-func (s *MessageService) sendMessage(ctx context.Context, msg model.Message) error {
-	payload := map[string]string{
-		"to":      msg.To,
-		"content": msg.Content,
+// fingerprint digests the fields that define a message submission. Expiry is
+// taken as requested rather than from msg because a TTL resolves to a
+// different instant on every retry.
+func fingerprint(msg model.Message, expiresAt *time.Time, ttlSeconds *int) string {
+	sendAt := ""
+	if msg.SendAt != nil {
+		sendAt = msg.SendAt.UTC().Format(time.RFC3339Nano)
+	}
+	parts := []string{msg.To, msg.Content, sendAt}
+	// Defaults are left out so keys stored before these fields existed
+	// still match their original request.
+	if msg.Priority != model.DefaultPriority {
+		parts = append(parts, strconv.Itoa(msg.Priority))
+	}
+	if msg.RecipientTimezone != "" {
+		parts = append(parts, "tz="+msg.RecipientTimezone)
+	}
+	if msg.Transactional {
+		parts = append(parts, "transactional")
+	}
+	if expiresAt != nil {
+		parts = append(parts, "expires="+expiresAt.UTC().Format(time.RFC3339Nano))
 	}
+	if ttlSeconds != nil {
+		parts = append(parts, "ttl="+strconv.Itoa(*ttlSeconds))
+	}
+	sum := sha256.Sum256([]byte(strings.Join(parts, "\x00")))
+	return hex.EncodeToString(sum[:])
+}
 
-	body, err := json.Marshal(payload)
+// batchFingerprint digests a batch submission in item order.
+func batchFingerprint(inputs []CreateMessageInput) (string, error) {
+	body, err := json.Marshal(inputs)
 	if err != nil {
-		return err
+		return "", err
 	}
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:]), nil
+}
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.webhookURL, bytes.NewReader(body))
+func newMessage(input CreateMessageInput) (model.Message, error) {
+	to, err := validRecipient(input.To)
 	if err != nil {
-		return err
+		return model.Message{}, err
 	}
-	req.Header.Set("Content-Type", "application/json")
-	if s.webhookAuthKey != "" {
-		req.Header.Set("x-ins-auth-key", s.webhookAuthKey)
+	if err := validateContent(input.Content); err != nil {
+		return model.Message{}, err
 	}
 
-	resp, err := s.client.Do(req)
+	msg := model.Message{To: to, Content: input.Content, Priority: model.DefaultPriority, Transactional: input.Transactional}
+	if input.Priority != nil {
+		if err := validatePriority(*input.Priority); err != nil {
+			return model.Message{}, err
+		}
+		msg.Priority = *input.Priority
+	}
+	if zone := strings.TrimSpace(input.RecipientTimezone); zone != "" {
+		if err := validateTimezone("recipient_timezone", zone); err != nil {
+			return model.Message{}, err
+		}
+		msg.RecipientTimezone = zone
+	}
+	if input.SendAt != nil {
+		sendAt := input.SendAt.UTC()
+		msg.SendAt = &sendAt
+	}
+
+	expiresAt, err := expiryFor(input, time.Now().UTC())
 	if err != nil {
-		return err
+		return model.Message{}, err
+	}
+	msg.ExpiresAt = expiresAt
+	return msg, nil
+}
+
+// expiryFor resolves the expiry of input. A TTL counts from send_at, or from
+// now for messages that are due immediately.
+func expiryFor(input CreateMessageInput, now time.Time) (*time.Time, error) {
+	dueAt := now
+	if input.SendAt != nil && input.SendAt.After(now) {
+		dueAt = input.SendAt.UTC()
+	}
+
+	var expiresAt time.Time
+	switch {
+	case input.ExpiresAt != nil && input.TTLSeconds != nil:
+		return nil, fmt.Errorf("%w: expires_at and ttl_seconds are mutually exclusive", ErrValidation)
+	case input.TTLSeconds != nil:
+		if *input.TTLSeconds <= 0 {
+			return nil, fmt.Errorf("%w: ttl_seconds must be positive", ErrValidation)
+		}
+		expiresAt = dueAt.Add(time.Duration(*input.TTLSeconds) * time.Second)
+	case input.ExpiresAt != nil:
+		expiresAt = input.ExpiresAt.UTC()
+		if !expiresAt.After(dueAt) {
+			return nil, fmt.Errorf("%w: expires_at must be after send_at and in the future", ErrValidation)
+		}
+	default:
+		return nil, nil
+	}
+	return &expiresAt, nil
+}
+
+// validRecipient trims to and checks it is present and short enough.
+func validRecipient(to string) (string, error) {
+	to = strings.TrimSpace(to)
+	if to == "" {
+		return "", fmt.Errorf("%w: to is required", ErrValidation)
+	}
+	if utf8.RuneCountInString(to) > maxRecipientLength {
+		return "", fmt.Errorf("%w: to must be at most %d characters", ErrValidation, maxRecipientLength)
+	}
+	return to, nil
+}
+
+func validateContent(content string) error {
+	if strings.TrimSpace(content) == "" {
+		return fmt.Errorf("%w: content is required", ErrValidation)
 	}
-	defer resp.Body.Close()
+	if utf8.RuneCountInString(content) > maxContentLength {
+		return fmt.Errorf("%w: content must be at most %d characters", ErrValidation, maxContentLength)
+	}
+	return nil
+}
 
-	if resp.StatusCode >= 300 {
-		return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+func validatePriority(priority int) error {
+	if priority < model.MinPriority || priority > model.MaxPriority {
+		return fmt.Errorf("%w: priority must be between %d and %d", ErrValidation, model.MinPriority, model.MaxPriority)
 	}
+	return nil
+}
 
-	var webhookResp webhookResponse
-	if err := json.NewDecoder(resp.Body).Decode(&webhookResp); err != nil {
-		return fmt.Errorf("decode webhook response: %w", err)
+// sendMessage delivers msg and records the outcome. Failed attempts are
+// rescheduled according to the retry policy until it is exhausted or the next
+// attempt would fall after the message expires. Outcomes are persisted even
+// if ctx expires mid-flight so an accepted message is never left looking
+// unsent. Messages no destination was tried for are released without using
+// an attempt; when every destination of their route is paused or has an open
+// breaker they are held back until the first one recovers, so they do not
+// crowd out messages for other routes in the meantime.
+func (s *MessageService) sendMessage(ctx context.Context, msg model.Message) error {
+	route, candidates := s.router.match(msg.To)
+	sent, err := s.deliver(ctx, msg, candidates)
+	attemptedAt := time.Now().UTC()
+	if err != nil && errors.Is(err, errNotAttempted) {
+		var retryAt *time.Time
+		if ctx.Err() == nil {
+			retryAt = s.availableAt(ctx, candidates)
+		}
+		s.releaseClaim(ctx, msg, retryAt)
+		return err
 	}
 
-	if webhookResp.Message != "Accepted" || webhookResp.MessageID == "" {
-		return fmt.Errorf("webhook rejected message %s", msg.ID)
+	ctx = context.WithoutCancel(ctx)
+	if err != nil {
+		deliveryErr := asDeliveryError(err)
+		failure := s.failureFor(msg, attemptedAt, deliveryErr)
+		failure.Route = route
+		failure.Provider = sent.destination
+		if recordErr := s.deps.repo.RecordFailure(ctx, msg.ID, s.leaseOwner, failure); recordErr != nil {
+			s.logger.Printf("failed to record delivery failure for %s: %v", msg.ID, recordErr)
+			return err
+		}
+		switch failure.Status {
+		case model.StatusFailed:
+			metrics.MessagesFailed.Add(1)
+		case model.StatusExpired:
+			metrics.MessagesExpired.Add(1)
+		}
+		return err
 	}
 
-	sentAt := time.Now().UTC()
-	if err := s.deps.repo.MarkAsSent(ctx, msg.ID, sentAt); err != nil {
+	success := repository.DeliverySuccess{
+		SentAt:    attemptedAt,
+		RemoteID:  sent.result.RemoteID,
+		Route:     route,
+		Provider:  sent.destination,
+		AttemptID: sent.attemptID,
+	}
+	if err := s.deps.repo.MarkAsSent(ctx, msg.ID, s.leaseOwner, success); err != nil {
+		// Keep the acceptance in the journal so lease reconciliation marks the
+		// message sent instead of sending it again.
+		s.finishAttempt(ctx, sent.attemptID, repository.AttemptOutcome{State: model.AttemptAccepted, RemoteID: sent.result.RemoteID})
 		return err
 	}
+	metrics.MessagesSent.Add(1)
 
-	if err := s.storeSentMetadata(ctx, msg.ID, webhookResp.MessageID, sentAt); err != nil {
+	if sent.result.RemoteID == "" {
+		return nil
+	}
+	if err := s.storeSentMetadata(ctx, msg.ID, sent.result.RemoteID, attemptedAt); err != nil {
 		s.logger.Printf("failed to store metadata in redis for %s: %v", msg.ID, err)
 	}
 
 	return nil
 }
 
-func (s *MessageService) storeSentMetadata(ctx context.Context, messageID uuid.UUID, remoteID string, sentAt time.Time) error {
-	key := fmt.Sprintf("sent_message:%s", remoteID)
-	values := map[string]interface{}{
-		"message_id": remoteID,
-		"local_id":   messageID.String(),
-		"sent_at":    sentAt.Format(time.RFC3339Nano),
+// dispatched describes the last provider call made for a message.
+type dispatched struct {
+	result      SendResult
+	destination string
+	attemptID   uuid.UUID
+}
+
+// deliver tries candidates in route order until one accepts msg and reports
+// the last call made. Every call is journaled before it is made. Retryable and
+// throttled failures fall over to the next available destination; permanent
+// failures are returned immediately because another gateway would reject the
+// message too. ctx only gates starting a call: a call that has started runs
+// detached from it, since aborting it cannot take back a request the gateway
+// may already have accepted.
+func (s *MessageService) deliver(ctx context.Context, msg model.Message, candidates []*destination) (dispatched, error) {
+	var last dispatched
+	var lastErr error
+	notAttempted := func(reason string, err error) (dispatched, error) {
+		if lastErr != nil {
+			return last, lastErr
+		}
+		return dispatched{}, fmt.Errorf("%w: %s: %w", errNotAttempted, reason, err)
 	}
 
-	if err := s.deps.redis.HSet(ctx, key, values).Err(); err != nil {
-		return err
+	for _, dest := range routeOrder(candidates, randomIntN) {
+		if !s.available(ctx, dest) {
+			continue
+		}
+		if err := s.waitForCapacity(ctx, dest.name()); err != nil {
+			return notAttempted("rate limit wait for "+dest.name(), err)
+		}
+		if err := dest.breaker.Allow(); err != nil {
+			continue
+		}
+
+		callCtx := context.WithoutCancel(ctx)
+		attemptID, err := s.deps.repo.BeginAttempt(callCtx, msg.ID, s.leaseOwner, dest.name())
+		if err != nil {
+			dest.breaker.Discard()
+			return notAttempted("journal attempt", err)
+		}
+		current := dispatched{destination: dest.name(), attemptID: attemptID}
+
+		result, err := dest.provider.Send(callCtx, msg)
+		// Only transient failures say anything about the destination's health.
+		dest.breaker.Record(err == nil || asDeliveryError(err).Class != ErrorClassRetryable)
+		if err == nil {
+			current.result = result
+			return current, nil
+		}
+
+		deliveryErr := asDeliveryError(err)
+		s.finishAttempt(callCtx, attemptID, repository.AttemptOutcome{State: model.AttemptFailed, Error: err.Error()})
+		s.pauseDestination(callCtx, dest.name(), deliveryErr.RetryAfter)
+		if deliveryErr.Class == ErrorClassPermanent {
+			return current, deliveryErr
+		}
+		s.logger.Printf("destination %s failed for %s: %v", dest.name(), msg.ID, err)
+		last, lastErr = current, deliveryErr
+	}
+
+	if lastErr == nil {
+		return dispatched{}, fmt.Errorf("%w: no destination available", errNotAttempted)
+	}
+	return last, lastErr
+}
+
+func (s *MessageService) finishAttempt(ctx context.Context, attemptID uuid.UUID, outcome repository.AttemptOutcome) {
+	if err := s.deps.repo.FinishAttempt(ctx, attemptID, outcome); err != nil {
+		s.logger.Printf("failed to journal %s outcome of attempt %s: %v", outcome.State, attemptID, err)
 	}
-	return nil
 }
 
-type webhookResponse struct {
-	Message   string `json:"message"`
-	MessageID string `json:"messageId"`
+// failureFor decides whether a failed message is retried later or gives up.
+// Permanent errors fail immediately, throttled attempts are rescheduled
+// without using the retry budget and retryable errors back off until the
+// policy is exhausted. A provider Retry-After always acts as a lower bound.
+func (s *MessageService) failureFor(msg model.Message, attemptedAt time.Time, err *DeliveryError) repository.DeliveryFailure {
+	failure := repository.DeliveryFailure{
+		Status:       model.StatusFailed,
+		AttemptedAt:  attemptedAt,
+		Error:        err.Error(),
+		ErrorClass:   string(err.Class),
+		CountAttempt: err.Class != ErrorClassThrottled,
+	}
+	if err.Class == ErrorClassPermanent {
+		return failure
+	}
+
+	attempts := msg.Attempts + 1
+	if failure.CountAttempt && s.retryPolicy.Exhausted(attempts) {
+		return failure
+	}
+
+	delay := max(s.retryPolicy.Delay(attempts), err.RetryAfter)
+	next := attemptedAt.Add(delay)
+	if msg.ExpiresAt != nil && !next.Before(*msg.ExpiresAt) {
+		failure.Status = model.StatusExpired
+		return failure
+	}
+	failure.Status = model.StatusQueued
+	failure.NextAttemptAt = &next
+	return failure
 }
@@ -0,0 +1,32 @@
+package service
+
+import (
+	"io"
+	"log"
+	"testing"
+)
+
+func TestLowPriorityShareReservesSlots(t *testing.T) {
+	cases := []struct {
+		fetchLimit int
+		share      float64
+		want       int
+	}{
+		{fetchLimit: 10, share: 0, want: 0},
+		{fetchLimit: 10, share: 0.25, want: 3},
+		{fetchLimit: 2, share: 0.1, want: 1},
+		{fetchLimit: 1, share: 0.5, want: 0},
+		{fetchLimit: 4, share: 0.99, want: 3},
+	}
+
+	for _, tc := range cases {
+		svc := NewMessageService(Dependencies{}, MessageServiceOptions{
+			FetchLimit:       tc.fetchLimit,
+			LowPriorityShare: tc.share,
+			Logger:           log.New(io.Discard, "", 0),
+		})
+		if svc.oldestFirst != tc.want {
+			t.Errorf("limit %d share %v: reserved %d slots, want %d", tc.fetchLimit, tc.share, svc.oldestFirst, tc.want)
+		}
+	}
+}
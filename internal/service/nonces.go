@@ -0,0 +1,37 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// NonceStore remembers the nonces of signed callbacks in Redis so a captured
+// request cannot be replayed while its timestamp is still accepted. Entries
+// live for ttl, which should cover the whole signature tolerance window on
+// both sides of the current time.
+type NonceStore struct {
+	redis redis.Cmdable
+	ttl   time.Duration
+}
+
+// NewNonceStore builds a NonceStore.
+func NewNonceStore(client redis.Cmdable, ttl time.Duration) *NonceStore {
+	return &NonceStore{redis: client, ttl: ttl}
+}
+
+// Claim records nonce and reports whether it had not been seen before.
+func (s *NonceStore) Claim(ctx context.Context, nonce string) (bool, error) {
+	return s.redis.SetNX(ctx, nonceKey(nonce), 1, s.ttl).Result()
+}
+
+// Release forgets nonce so the same request can be retried, e.g. when it was
+// not applied.
+func (s *NonceStore) Release(ctx context.Context, nonce string) error {
+	return s.redis.Del(ctx, nonceKey(nonce)).Err()
+}
+
+func nonceKey(nonce string) string {
+	return "callback_nonce:" + nonce
+}
@@ -0,0 +1,22 @@
+package service
+
+import (
+	"context"
+
+	"automessaging/internal/model"
+)
+
+// Provider delivers messages to an external gateway. Implementations should
+// return a *DeliveryError so failures are classified correctly; any other
+// error is treated as retryable.
+type Provider interface {
+	// Name identifies the provider for rate limiting, circuit breaking and logs.
+	Name() string
+	// Send delivers msg and reports the gateway's identifier for it.
+	Send(ctx context.Context, msg model.Message) (SendResult, error)
+}
+
+// SendResult is what a provider reports for an accepted message.
+type SendResult struct {
+	RemoteID string
+}
@@ -0,0 +1,92 @@
+package service
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"automessaging/internal/model"
+	"automessaging/internal/repository"
+	"automessaging/internal/timezone"
+)
+
+// QuietHours holds back non-transactional messages during a daily window in
+// the recipient's local time, e.g. 21:00 to 08:00. Start and End are offsets
+// from local midnight; the window wraps past midnight when Start is after
+// End. Timezones maps recipient prefixes (e.g. "+90") to the IANA zone
+// assumed for numbers that start with them, longest prefix first. Recipients
+// with neither an explicit nor an inferred zone use DefaultTimezone.
+type QuietHours struct {
+	Start           time.Duration
+	End             time.Duration
+	DefaultTimezone string
+	Timezones       map[string]string
+}
+
+type zonePrefix struct {
+	prefix string
+	zone   string
+}
+
+// quietHours is the compiled form of QuietHours.
+type quietHours struct {
+	window repository.QuietHours
+	zones  []zonePrefix
+}
+
+func newQuietHours(cfg *QuietHours) *quietHours {
+	if cfg == nil {
+		return nil
+	}
+
+	defaultZone := cfg.DefaultTimezone
+	if defaultZone == "" {
+		defaultZone = "UTC"
+	}
+	q := &quietHours{window: repository.QuietHours{Start: cfg.Start, End: cfg.End, DefaultTimezone: defaultZone}}
+	for prefix, zone := range cfg.Timezones {
+		q.zones = append(q.zones, zonePrefix{prefix: normalizeRecipient(prefix), zone: zone})
+	}
+	sort.SliceStable(q.zones, func(i, j int) bool {
+		return len(q.zones[i].prefix) > len(q.zones[j].prefix)
+	})
+	return q
+}
+
+// infer returns the zone configured for the longest prefix of recipient,
+// empty when none matches or quiet hours are off.
+func (q *quietHours) infer(recipient string) string {
+	if q == nil {
+		return ""
+	}
+	number := normalizeRecipient(recipient)
+	for _, z := range q.zones {
+		if strings.HasPrefix(number, z.prefix) {
+			return z.zone
+		}
+	}
+	return ""
+}
+
+// localize fills in the recipient time zone of msg from its number when the
+// caller did not give one. Unmatched recipients keep an empty zone so they
+// follow the default even if it is changed later.
+func (s *MessageService) localize(msg *model.Message) {
+	if msg.RecipientTimezone != "" {
+		return
+	}
+	msg.RecipientTimezone = s.quietHours.infer(msg.To)
+	msg.RecipientTimezoneInferred = msg.RecipientTimezone != ""
+}
+
+// validateTimezone checks the zone given in field is an IANA time zone name.
+func validateTimezone(field, zone string) error {
+	if len(zone) > timezone.MaxLength {
+		return fmt.Errorf("%w: %s must be at most %d characters", ErrValidation, field, timezone.MaxLength)
+	}
+	if _, err := timezone.Load(zone); err != nil {
+		return fmt.Errorf("%w: %s %v", ErrValidation, field, err)
+	}
+	return nil
+}
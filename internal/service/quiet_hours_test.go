@@ -0,0 +1,154 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"automessaging/internal/model"
+	"automessaging/internal/repository"
+)
+
+var usZones = &QuietHours{Start: 21 * time.Hour, End: 8 * time.Hour, Timezones: map[string]string{"+1": "America/New_York"}}
+
+func TestQuietHoursInferTimezone(t *testing.T) {
+	svc := NewMessageService(Dependencies{}, MessageServiceOptions{
+		QuietHours: &QuietHours{
+			Start: 21 * time.Hour,
+			End:   8 * time.Hour,
+			Timezones: map[string]string{
+				"+1":    "America/New_York",
+				"+1808": "Pacific/Honolulu",
+				"+90":   "Europe/Istanbul",
+			},
+		},
+		Logger: log.New(io.Discard, "", 0),
+	})
+
+	cases := []struct {
+		to       string
+		explicit string
+		want     string
+	}{
+		{to: "+1 (212) 555-0100", want: "America/New_York"},
+		{to: "+18085550100", want: "Pacific/Honolulu"},
+		{to: "+905551112233", explicit: "Europe/London", want: "Europe/London"},
+		{to: "+445551112233", want: ""},
+	}
+
+	for _, tc := range cases {
+		msg, err := newMessage(CreateMessageInput{To: tc.to, Content: "hello", RecipientTimezone: tc.explicit})
+		if err != nil {
+			t.Fatalf("%s: %v", tc.to, err)
+		}
+		svc.localize(&msg)
+		if msg.RecipientTimezone != tc.want {
+			t.Errorf("%s: got zone %q, want %q", tc.to, msg.RecipientTimezone, tc.want)
+		}
+	}
+
+	if svc.quietHours.window.DefaultTimezone != "UTC" {
+		t.Errorf("expected the default zone to fall back to UTC, got %q", svc.quietHours.window.DefaultTimezone)
+	}
+}
+
+func TestRecipientTimezoneValidation(t *testing.T) {
+	for _, zone := range []string{"Mars/Olympus", "Local"} {
+		_, err := newMessage(CreateMessageInput{To: "+905551112233", Content: "hello", RecipientTimezone: zone})
+		if !errors.Is(err, ErrValidation) {
+			t.Errorf("%s: expected a validation error, got %v", zone, err)
+		}
+	}
+}
+
+func TestScheduleTimezoneValidation(t *testing.T) {
+	for _, zone := range []string{"Mars/Olympus", "Local"} {
+		_, err := newSchedule(CreateScheduleInput{To: "+905551112233", Content: "hello", CronExpression: "0 9 * * MON", Timezone: zone}, time.Now())
+		if !errors.Is(err, ErrValidation) {
+			t.Errorf("%s: expected a validation error, got %v", zone, err)
+		}
+	}
+}
+
+func TestFingerprintIncludesQuietHoursFields(t *testing.T) {
+	base, err := newMessage(CreateMessageInput{To: "+905551112233", Content: "hello"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	zoned := base
+	zoned.RecipientTimezone = "Europe/Istanbul"
+	transactional := base
+	transactional.Transactional = true
+
+	if fingerprint(base, nil, nil) == fingerprint(zoned, nil, nil) || fingerprint(base, nil, nil) == fingerprint(transactional, nil, nil) {
+		t.Fatal("expected the time zone and transactional flag to change the fingerprint")
+	}
+}
+
+func TestRecurringScheduleInfersRecipientTimezone(t *testing.T) {
+	cases := []struct {
+		timezone     string
+		wantZone     string
+		wantInferred bool
+	}{
+		{timezone: "", wantZone: "America/New_York", wantInferred: true},
+		{timezone: "Europe/Berlin", wantZone: "Europe/Berlin"},
+	}
+
+	for _, tc := range cases {
+		repo := &scheduleRepo{}
+		svc := NewScheduleService(repo, usZones)
+
+		_, err := svc.CreateSchedule(context.Background(), CreateScheduleInput{
+			To: "+12125550100", Content: "standup", CronExpression: "0 9 * * MON", Timezone: tc.timezone,
+		})
+		if err != nil {
+			t.Fatalf("timezone %q: %v", tc.timezone, err)
+		}
+		if repo.created.RecipientTimezone != tc.wantZone || repo.created.RecipientTimezoneInferred != tc.wantInferred {
+			t.Errorf("timezone %q: recipient zone %q (inferred %v), want %q (inferred %v)", tc.timezone,
+				repo.created.RecipientTimezone, repo.created.RecipientTimezoneInferred, tc.wantZone, tc.wantInferred)
+		}
+	}
+}
+
+func TestEditMessageInfersTimezoneOfNewRecipient(t *testing.T) {
+	repo := &editRepo{}
+	svc := NewMessageService(Dependencies{Repo: repo}, MessageServiceOptions{QuietHours: usZones, Logger: log.New(io.Discard, "", 0)})
+
+	for to, want := range map[string]string{"+12125550100": "America/New_York", "+905551112233": ""} {
+		if _, err := svc.EditMessage(context.Background(), uuid.New(), 1, EditMessageInput{To: &to}); err != nil {
+			t.Fatalf("%s: %v", to, err)
+		}
+		if got := repo.edit.RecipientTimezone; got == nil || *got != want {
+			t.Errorf("%s: edit carries zone %v, want %q", to, got, want)
+		}
+	}
+}
+
+// scheduleRepo records the schedule passed to CreateSchedule.
+type scheduleRepo struct {
+	repository.ScheduleRepository
+	created model.RecurringSchedule
+}
+
+func (r *scheduleRepo) CreateSchedule(_ context.Context, schedule *model.RecurringSchedule) error {
+	r.created = *schedule
+	return nil
+}
+
+// editRepo accepts every edit and records the last one.
+type editRepo struct {
+	repository.MessageRepository
+	edit repository.MessageEdit
+}
+
+func (r *editRepo) UpdateQueued(_ context.Context, id uuid.UUID, version int, edit repository.MessageEdit) (model.Message, error) {
+	r.edit = edit
+	return model.Message{ID: id, Status: model.StatusQueued, Version: version + 1}, nil
+}
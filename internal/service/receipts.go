@@ -0,0 +1,81 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"automessaging/internal/model"
+	"automessaging/internal/repository"
+)
+
+const maxCarrierErrorCode = 32
+
+// receiptStatuses maps the status vocabularies gateways commonly use onto the
+// two final states we track.
+var receiptStatuses = map[string]model.MessageStatus{
+	"delivered":   model.StatusDelivered,
+	"delivrd":     model.StatusDelivered,
+	"undelivered": model.StatusUndelivered,
+	"undeliv":     model.StatusUndelivered,
+	"failed":      model.StatusUndelivered,
+	"rejected":    model.StatusUndelivered,
+	"expired":     model.StatusUndelivered,
+}
+
+// DeliveryReceiptInput is a delivery receipt (DLR) reported by a provider.
+// MessageID is the provider's id returned when the message was accepted.
+// Timestamp defaults to the time the receipt is received.
+type DeliveryReceiptInput struct {
+	MessageID string     `json:"message_id"`
+	Status    string     `json:"status"`
+	ErrorCode string     `json:"error_code,omitempty"`
+	Timestamp *time.Time `json:"timestamp,omitempty"`
+}
+
+// DeliveryReceiptResult reports how a receipt was applied. Applied is false
+// when a newer receipt had already been recorded.
+type DeliveryReceiptResult struct {
+	ID      uuid.UUID           `json:"id"`
+	Status  model.MessageStatus `json:"status"`
+	Applied bool                `json:"applied"`
+}
+
+// RecordDeliveryReceipt moves the message a provider accepted under
+// input.MessageID to delivered or undelivered.
+func (s *MessageService) RecordDeliveryReceipt(ctx context.Context, input DeliveryReceiptInput) (DeliveryReceiptResult, error) {
+	remoteID := strings.TrimSpace(input.MessageID)
+	if remoteID == "" {
+		return DeliveryReceiptResult{}, fmt.Errorf("%w: message_id is required", ErrValidation)
+	}
+	status, ok := receiptStatuses[strings.ToLower(strings.TrimSpace(input.Status))]
+	if !ok {
+		return DeliveryReceiptResult{}, fmt.Errorf("%w: unsupported receipt status %q", ErrValidation, input.Status)
+	}
+	if len(input.ErrorCode) > maxCarrierErrorCode {
+		return DeliveryReceiptResult{}, fmt.Errorf("%w: error_code must be at most %d characters", ErrValidation, maxCarrierErrorCode)
+	}
+
+	receipt := repository.DeliveryReceipt{
+		Status:    status,
+		At:        time.Now().UTC(),
+		ErrorCode: input.ErrorCode,
+	}
+	if input.Timestamp != nil {
+		receipt.At = input.Timestamp.UTC()
+	}
+
+	id, err := s.localIDForRemote(ctx, remoteID)
+	if err != nil {
+		return DeliveryReceiptResult{}, err
+	}
+
+	applied, err := s.deps.repo.RecordReceipt(ctx, id, receipt)
+	if err != nil {
+		return DeliveryReceiptResult{}, err
+	}
+	return DeliveryReceiptResult{ID: id, Status: status, Applied: applied}, nil
+}
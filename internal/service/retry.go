@@ -0,0 +1,88 @@
+package service
+
+import (
+	"math"
+	"math/rand/v2"
+	"time"
+)
+
+// RetryPolicy controls how failed deliveries are rescheduled.
+type RetryPolicy struct {
+	// BaseDelay is the wait before the second attempt.
+	BaseDelay time.Duration
+	// Multiplier grows the delay after every further failure.
+	Multiplier float64
+	// MaxDelay caps the computed delay before jitter is applied.
+	MaxDelay time.Duration
+	// Jitter randomizes the delay by up to ±Jitter (a fraction of the delay).
+	Jitter float64
+	// MaxAttempts is the total number of attempts before a message fails permanently.
+	MaxAttempts int
+}
+
+// DefaultRetryPolicy returns the policy used when none is configured.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		BaseDelay:   30 * time.Second,
+		Multiplier:  2,
+		MaxDelay:    time.Hour,
+		Jitter:      0.2,
+		MaxAttempts: 5,
+	}
+}
+
+// Exhausted reports whether a message with the given number of attempts
+// should no longer be retried.
+func (p RetryPolicy) Exhausted(attempts int) bool {
+	return p.MaxAttempts > 0 && attempts >= p.MaxAttempts
+}
+
+// Delay returns how long to wait after the given number of failed attempts.
+func (p RetryPolicy) Delay(attempts int) time.Duration {
+	return p.delay(attempts, rand.Float64)
+}
+
+func (p RetryPolicy) delay(attempts int, random func() float64) time.Duration {
+	if attempts < 1 {
+		attempts = 1
+	}
+
+	multiplier := p.Multiplier
+	if multiplier < 1 {
+		multiplier = 1
+	}
+
+	delay := float64(p.BaseDelay) * math.Pow(multiplier, float64(attempts-1))
+	if p.MaxDelay > 0 && delay > float64(p.MaxDelay) {
+		delay = float64(p.MaxDelay)
+	}
+
+	if p.Jitter > 0 {
+		delay += delay * p.Jitter * (2*random() - 1)
+	}
+	if delay < 0 {
+		delay = 0
+	}
+
+	return time.Duration(delay)
+}
+
+func (p RetryPolicy) withDefaults() RetryPolicy {
+	def := DefaultRetryPolicy()
+	if p.BaseDelay <= 0 {
+		p.BaseDelay = def.BaseDelay
+	}
+	if p.Multiplier <= 0 {
+		p.Multiplier = def.Multiplier
+	}
+	if p.MaxDelay <= 0 {
+		p.MaxDelay = def.MaxDelay
+	}
+	if p.Jitter < 0 {
+		p.Jitter = 0
+	}
+	if p.MaxAttempts <= 0 {
+		p.MaxAttempts = def.MaxAttempts
+	}
+	return p
+}
@@ -0,0 +1,40 @@
+package service
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRetryPolicyDelayGrowsAndCaps(t *testing.T) {
+	policy := RetryPolicy{BaseDelay: 10 * time.Second, Multiplier: 2, MaxDelay: time.Minute, MaxAttempts: 5}
+	noJitter := func() float64 { return 0.5 }
+
+	expected := []time.Duration{10 * time.Second, 20 * time.Second, 40 * time.Second, time.Minute, time.Minute}
+	for i, want := range expected {
+		if got := policy.delay(i+1, noJitter); got != want {
+			t.Fatalf("attempt %d: expected %v, got %v", i+1, want, got)
+		}
+	}
+}
+
+func TestRetryPolicyJitterBounds(t *testing.T) {
+	policy := RetryPolicy{BaseDelay: 10 * time.Second, Multiplier: 2, MaxDelay: time.Minute, Jitter: 0.5}
+
+	if got := policy.delay(1, func() float64 { return 0 }); got != 5*time.Second {
+		t.Fatalf("expected lower bound 5s, got %v", got)
+	}
+	if got := policy.delay(1, func() float64 { return 1 }); got != 15*time.Second {
+		t.Fatalf("expected upper bound 15s, got %v", got)
+	}
+}
+
+func TestRetryPolicyExhausted(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 3}
+
+	if policy.Exhausted(2) {
+		t.Fatal("expected 2 attempts to allow another retry")
+	}
+	if !policy.Exhausted(3) {
+		t.Fatal("expected 3 attempts to exhaust the policy")
+	}
+}
@@ -0,0 +1,79 @@
+package service
+
+import (
+	"log"
+	"sort"
+	"strings"
+)
+
+// Route sends recipients whose number starts with Prefix (e.g. "+90") to the
+// named destinations. The longest matching prefix wins.
+type Route struct {
+	Prefix       string
+	Destinations []string
+}
+
+type route struct {
+	prefix       string
+	destinations []*destination
+}
+
+// router resolves the destinations a recipient may be sent through.
+// Recipients no route matches may use every destination.
+type router struct {
+	routes []route
+	all    []*destination
+}
+
+func newRouter(routes []Route, destinations []*destination, logger *log.Logger) *router {
+	byName := make(map[string]*destination, len(destinations))
+	for _, dest := range destinations {
+		byName[dest.name()] = dest
+	}
+
+	r := &router{all: destinations}
+	for _, configured := range routes {
+		compiled := route{prefix: normalizeRecipient(configured.Prefix)}
+		for _, name := range configured.Destinations {
+			dest, ok := byName[name]
+			if !ok {
+				logger.Printf("route %s references unknown destination %s, ignoring it", configured.Prefix, name)
+				continue
+			}
+			compiled.destinations = append(compiled.destinations, dest)
+		}
+		if len(compiled.destinations) == 0 {
+			logger.Printf("route %s has no usable destinations, ignoring it", configured.Prefix)
+			continue
+		}
+		r.routes = append(r.routes, compiled)
+	}
+
+	sort.SliceStable(r.routes, func(i, j int) bool {
+		return len(r.routes[i].prefix) > len(r.routes[j].prefix)
+	})
+	return r
+}
+
+// match returns the prefix of the route chosen for recipient, empty when none
+// matched, and the destinations it allows.
+func (r *router) match(recipient string) (string, []*destination) {
+	number := normalizeRecipient(recipient)
+	for _, rt := range r.routes {
+		if strings.HasPrefix(number, rt.prefix) {
+			return rt.prefix, rt.destinations
+		}
+	}
+	return "", r.all
+}
+
+// normalizeRecipient drops formatting so "+1 (555) 010-0000" and
+// "+15550100000" match the same prefixes.
+func normalizeRecipient(number string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '+' || (r >= '0' && r <= '9') {
+			return r
+		}
+		return -1
+	}, number)
+}
@@ -0,0 +1,38 @@
+package service
+
+import (
+	"io"
+	"log"
+	"testing"
+)
+
+func TestRouterLongestPrefixMatch(t *testing.T) {
+	us := &destination{provider: namedProvider("us")}
+	tr := &destination{provider: namedProvider("tr")}
+	trMobile := &destination{provider: namedProvider("tr-mobile")}
+	all := []*destination{us, tr, trMobile}
+
+	r := newRouter([]Route{
+		{Prefix: "+1", Destinations: []string{"us"}},
+		{Prefix: "+90", Destinations: []string{"tr"}},
+		{Prefix: "+905", Destinations: []string{"tr-mobile", "tr"}},
+		{Prefix: "+44", Destinations: []string{"missing"}},
+	}, all, log.New(io.Discard, "", 0))
+
+	cases := []struct {
+		to     string
+		prefix string
+		names  string
+	}{
+		{to: "+1 (555) 010-0000", prefix: "+1", names: "us"},
+		{to: "+902121234567", prefix: "+90", names: "tr"},
+		{to: "+905551234567", prefix: "+905", names: "tr-mobile,tr"},
+		{to: "+447700900000", prefix: "", names: "us,tr,tr-mobile"},
+	}
+	for _, tc := range cases {
+		prefix, dests := r.match(tc.to)
+		if prefix != tc.prefix || names(dests) != tc.names {
+			t.Fatalf("%s: expected %q -> %s, got %q -> %s", tc.to, tc.prefix, tc.names, prefix, names(dests))
+		}
+	}
+}
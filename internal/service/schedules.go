@@ -0,0 +1,176 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"automessaging/internal/model"
+	"automessaging/internal/recurrence"
+	"automessaging/internal/repository"
+)
+
+// ErrScheduleNotFound is returned when the requested recurring schedule does
+// not exist.
+var ErrScheduleNotFound = errors.New("schedule not found")
+
+// ScheduleService manages recurring schedules. Their messages are created by
+// scheduler.Materializer.
+type ScheduleService struct {
+	repo       repository.ScheduleRepository
+	quietHours *quietHours
+}
+
+// NewScheduleService builds a ScheduleService. quiet supplies the prefix map
+// used to infer a recipient's time zone and should match the one given to
+// the MessageService; nil turns inference off.
+func NewScheduleService(repo repository.ScheduleRepository, quiet *QuietHours) *ScheduleService {
+	return &ScheduleService{repo: repo, quietHours: newQuietHours(quiet)}
+}
+
+// CreateScheduleInput describes a recurring message. Timezone is an IANA
+// name and defaults to UTC; when given it is also the recipient's zone for
+// quiet hours, otherwise that zone is inferred from To like for messages. StartsAt defaults to now; occurrences before now
+// are never created. TTLSeconds, when set, expires each occurrence that long
+// after it is due.
+type CreateScheduleInput struct {
+	To             string     `json:"to"`
+	Content        string     `json:"content"`
+	CronExpression string     `json:"cron_expression"`
+	Timezone       string     `json:"timezone,omitempty"`
+	Priority       *int       `json:"priority,omitempty"`
+	TTLSeconds     *int       `json:"ttl_seconds,omitempty"`
+	StartsAt       *time.Time `json:"starts_at,omitempty"`
+	EndsAt         *time.Time `json:"ends_at,omitempty"`
+}
+
+// SchedulesResult captures paginated recurring schedules.
+type SchedulesResult struct {
+	Schedules []model.RecurringSchedule `json:"schedules"`
+	Total     int                       `json:"total"`
+	Page      int                       `json:"page"`
+	Limit     int                       `json:"limit"`
+}
+
+// CreateSchedule validates input and stores the schedule with its first
+// occurrence.
+func (s *ScheduleService) CreateSchedule(ctx context.Context, input CreateScheduleInput) (model.RecurringSchedule, error) {
+	schedule, err := newSchedule(input, time.Now().UTC())
+	if err != nil {
+		return model.RecurringSchedule{}, err
+	}
+	if schedule.RecipientTimezone == "" {
+		schedule.RecipientTimezone = s.quietHours.infer(schedule.To)
+		schedule.RecipientTimezoneInferred = schedule.RecipientTimezone != ""
+	}
+	if err := s.repo.CreateSchedule(ctx, &schedule); err != nil {
+		return model.RecurringSchedule{}, err
+	}
+	return schedule, nil
+}
+
+// GetSchedule returns the schedule with the given id.
+func (s *ScheduleService) GetSchedule(ctx context.Context, id uuid.UUID) (model.RecurringSchedule, error) {
+	schedule, err := s.repo.FindScheduleByID(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return model.RecurringSchedule{}, fmt.Errorf("%w: no schedule with id %s", ErrScheduleNotFound, id)
+	}
+	return schedule, err
+}
+
+// ListSchedules returns paginated schedules, newest first.
+func (s *ScheduleService) ListSchedules(ctx context.Context, page, limit int) (SchedulesResult, error) {
+	page, limit = normalizePage(page, limit)
+
+	items, total, err := s.repo.ListSchedules(ctx, (page-1)*limit, limit)
+	if err != nil {
+		return SchedulesResult{}, err
+	}
+
+	return SchedulesResult{
+		Schedules: items,
+		Total:     total,
+		Page:      page,
+		Limit:     limit,
+	}, nil
+}
+
+// DeactivateSchedule stops a schedule. Messages it already created stay
+// queued and can be cancelled individually.
+func (s *ScheduleService) DeactivateSchedule(ctx context.Context, id uuid.UUID) (model.RecurringSchedule, error) {
+	schedule, err := s.repo.DeactivateSchedule(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return model.RecurringSchedule{}, fmt.Errorf("%w: no schedule with id %s", ErrScheduleNotFound, id)
+	}
+	return schedule, err
+}
+
+func newSchedule(input CreateScheduleInput, now time.Time) (model.RecurringSchedule, error) {
+	to, err := validRecipient(input.To)
+	if err != nil {
+		return model.RecurringSchedule{}, err
+	}
+	if err := validateContent(input.Content); err != nil {
+		return model.RecurringSchedule{}, err
+	}
+
+	schedule := model.RecurringSchedule{
+		To:             to,
+		Content:        input.Content,
+		CronExpression: input.CronExpression,
+		Timezone:       input.Timezone,
+		Priority:       model.DefaultPriority,
+		StartsAt:       now,
+	}
+	if schedule.Timezone == "" {
+		schedule.Timezone = "UTC"
+	} else {
+		if err := validateTimezone("timezone", schedule.Timezone); err != nil {
+			return model.RecurringSchedule{}, err
+		}
+		schedule.RecipientTimezone = schedule.Timezone
+	}
+	if _, err := recurrence.Parse(schedule.CronExpression, schedule.Timezone); err != nil {
+		return model.RecurringSchedule{}, fmt.Errorf("%w: %v", ErrValidation, err)
+	}
+
+	if input.Priority != nil {
+		if err := validatePriority(*input.Priority); err != nil {
+			return model.RecurringSchedule{}, err
+		}
+		schedule.Priority = *input.Priority
+	}
+	if input.TTLSeconds != nil {
+		if *input.TTLSeconds <= 0 {
+			return model.RecurringSchedule{}, fmt.Errorf("%w: ttl_seconds must be positive", ErrValidation)
+		}
+		schedule.TTLSeconds = input.TTLSeconds
+	}
+	if input.StartsAt != nil {
+		schedule.StartsAt = input.StartsAt.UTC()
+	}
+	if input.EndsAt != nil {
+		endsAt := input.EndsAt.UTC()
+		if !endsAt.After(schedule.StartsAt) {
+			return model.RecurringSchedule{}, fmt.Errorf("%w: ends_at must be after starts_at", ErrValidation)
+		}
+		schedule.EndsAt = &endsAt
+	}
+
+	// Cron occurrences fall on whole seconds and Next is exclusive, so
+	// stepping back one second lets an occurrence at starts_at itself count.
+	from := schedule.StartsAt
+	if from.Before(now) {
+		from = now
+	}
+	first, ok := recurrence.NextOccurrence(schedule, from.Add(-time.Second))
+	if !ok {
+		return model.RecurringSchedule{}, fmt.Errorf("%w: the schedule has no occurrence between starts_at and ends_at", ErrValidation)
+	}
+	schedule.NextOccurrenceAt = &first
+	return schedule, nil
+}
@@ -0,0 +1,79 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+
+	"automessaging/internal/model"
+)
+
+const defaultRehydrateBatch = 500
+
+// storeSentMetadata caches the remote id mapping in Redis. Postgres holds the
+// same mapping in remote_message_id, so a lost entry can always be rebuilt.
+func (s *MessageService) storeSentMetadata(ctx context.Context, messageID uuid.UUID, remoteID string, sentAt time.Time) error {
+	key := sentMetadataKey(remoteID)
+	values := map[string]interface{}{
+		"message_id": remoteID,
+		"local_id":   messageID.String(),
+		"sent_at":    sentAt.Format(time.RFC3339Nano),
+	}
+
+	_, err := s.deps.redis.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, key, values)
+		if s.metadataTTL > 0 {
+			pipe.Expire(ctx, key, s.metadataTTL)
+		}
+		return nil
+	})
+	return err
+}
+
+// cacheSentMetadata repopulates the cache entry for msg after a miss.
+func (s *MessageService) cacheSentMetadata(ctx context.Context, msg model.Message) {
+	if msg.RemoteMessageID == "" || msg.SentAt == nil {
+		return
+	}
+	if err := s.storeSentMetadata(ctx, msg.ID, msg.RemoteMessageID, *msg.SentAt); err != nil {
+		s.logger.Printf("failed to repopulate metadata in redis for %s: %v", msg.RemoteMessageID, err)
+	}
+}
+
+// RehydrateSentMetadata rebuilds the Redis remote-id cache from Postgres for
+// messages sent at or after since, e.g. after a Redis flush. It returns the
+// number of entries written.
+func (s *MessageService) RehydrateSentMetadata(ctx context.Context, since time.Time, batchSize int) (int, error) {
+	if batchSize <= 0 {
+		batchSize = defaultRehydrateBatch
+	}
+
+	written := 0
+	afterSentAt, afterID := since, uuid.Nil
+	for {
+		batch, err := s.deps.repo.ListAccepted(ctx, afterSentAt, afterID, batchSize)
+		if err != nil {
+			return written, err
+		}
+
+		for _, msg := range batch {
+			if err := s.storeSentMetadata(ctx, msg.ID, msg.RemoteMessageID, *msg.SentAt); err != nil {
+				return written, fmt.Errorf("store metadata for %s: %w", msg.ID, err)
+			}
+			written++
+		}
+
+		if len(batch) < batchSize {
+			return written, nil
+		}
+		last := batch[len(batch)-1]
+		afterSentAt, afterID = *last.SentAt, last.ID
+	}
+}
+
+func sentMetadataKey(remoteID string) string {
+	return fmt.Sprintf("sent_message:%s", remoteID)
+}
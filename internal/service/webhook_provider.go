@@ -0,0 +1,240 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"automessaging/internal/model"
+	"automessaging/pkg/webhooksig"
+)
+
+const (
+	defaultBodyTemplate = `{"to":{{json .To}},"content":{{json .Content}}}`
+	maxResponseBytes    = 1 << 20
+)
+
+// WebhookMapping describes how messages are encoded for a gateway and how its
+// responses are interpreted.
+type WebhookMapping struct {
+	// BodyTemplate is a text/template rendered with the message. The json
+	// function quotes values for JSON bodies.
+	BodyTemplate string
+	// ContentType is sent with the request body.
+	ContentType string
+	// RemoteIDPath is the dot-separated path of the gateway message id in
+	// the JSON response, e.g. "data.messages.0.id".
+	RemoteIDPath string
+	// SuccessPath and SuccessValue form the acceptance predicate: the value at
+	// SuccessPath must equal SuccessValue. An empty SuccessPath accepts any 2xx.
+	SuccessPath  string
+	SuccessValue string
+}
+
+// DefaultWebhookMapping matches the original `{"to","content"}` request and
+// `{"message":"Accepted","messageId":...}` response contract.
+func DefaultWebhookMapping() WebhookMapping {
+	return WebhookMapping{
+		BodyTemplate: defaultBodyTemplate,
+		ContentType:  "application/json",
+		RemoteIDPath: "messageId",
+		SuccessPath:  "message",
+		SuccessValue: "Accepted",
+	}
+}
+
+// WebhookProviderOptions configures a WebhookProvider. Name is required; it
+// keys rate limits, pauses and circuit breakers, and the configuration names
+// unnamed destinations after their host.
+type WebhookProviderOptions struct {
+	Name    string
+	URL     string
+	AuthKey string
+	Timeout time.Duration
+	// MaxIdleConns bounds kept-alive connections; match it to the dispatch concurrency.
+	MaxIdleConns int
+	// Signer, when set, adds HMAC signature headers to requests.
+	Signer  *webhooksig.Signer
+	Mapping WebhookMapping
+}
+
+// WebhookProvider delivers messages by POSTing them to an HTTP endpoint.
+type WebhookProvider struct {
+	name     string
+	url      string
+	authKey  string
+	client   *http.Client
+	signer   *webhooksig.Signer
+	mapping  WebhookMapping
+	template *template.Template
+}
+
+var _ Provider = (*WebhookProvider)(nil)
+
+// NewWebhookProvider builds a WebhookProvider. An empty BodyTemplate or
+// ContentType falls back to DefaultWebhookMapping. The other mapping fields
+// are used as given because empty values are meaningful: without a
+// SuccessPath any 2xx response is accepted, and without a RemoteIDPath no
+// gateway id is recorded.
+func NewWebhookProvider(opts WebhookProviderOptions) (*WebhookProvider, error) {
+	if opts.URL == "" {
+		return nil, errors.New("webhook URL is not configured")
+	}
+	if opts.Name == "" {
+		return nil, errors.New("webhook name is not configured")
+	}
+	name := opts.Name
+
+	timeout := opts.Timeout
+	if timeout == 0 {
+		timeout = 15 * time.Second
+	}
+
+	mapping := opts.Mapping
+	def := DefaultWebhookMapping()
+	if mapping.BodyTemplate == "" {
+		mapping.BodyTemplate = def.BodyTemplate
+	}
+	if mapping.ContentType == "" {
+		mapping.ContentType = def.ContentType
+	}
+
+	tmpl, err := template.New(name).Option("missingkey=error").Funcs(template.FuncMap{
+		"json": func(v interface{}) (string, error) {
+			b, err := json.Marshal(v)
+			return string(b), err
+		},
+	}).Parse(mapping.BodyTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("parse body template for %s: %w", name, err)
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if opts.MaxIdleConns > 0 {
+		transport.MaxIdleConnsPerHost = opts.MaxIdleConns
+	}
+
+	return &WebhookProvider{
+		name:     name,
+		url:      opts.URL,
+		authKey:  opts.AuthKey,
+		client:   &http.Client{Timeout: timeout, Transport: transport},
+		signer:   opts.Signer,
+		mapping:  mapping,
+		template: tmpl,
+	}, nil
+}
+
+// Name implements Provider.
+func (p *WebhookProvider) Name() string {
+	return p.name
+}
+
+// Send implements Provider.
+func (p *WebhookProvider) Send(ctx context.Context, msg model.Message) (SendResult, error) {
+	var body bytes.Buffer
+	if err := p.template.Execute(&body, msg); err != nil {
+		return SendResult{}, permanent(fmt.Errorf("render webhook body: %w", err))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.url, bytes.NewReader(body.Bytes()))
+	if err != nil {
+		return SendResult{}, permanent(err)
+	}
+	req.Header.Set("Content-Type", p.mapping.ContentType)
+	if p.authKey != "" {
+		req.Header.Set("x-ins-auth-key", p.authKey)
+	}
+	if p.signer != nil {
+		if err := p.signer.Sign(req.Header, body.Bytes()); err != nil {
+			return SendResult{}, retryable(fmt.Errorf("sign webhook request: %w", err))
+		}
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return SendResult{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return SendResult{}, classifyStatus(resp, time.Now())
+	}
+
+	return p.interpret(resp.Body, msg)
+}
+
+// interpret applies the mapping's success predicate and remote id path to a 2xx response.
+func (p *WebhookProvider) interpret(body io.Reader, msg model.Message) (SendResult, error) {
+	if p.mapping.SuccessPath == "" && p.mapping.RemoteIDPath == "" {
+		return SendResult{}, nil
+	}
+
+	// Numbers stay json.Number so large numeric ids keep every digit.
+	dec := json.NewDecoder(io.LimitReader(body, maxResponseBytes))
+	dec.UseNumber()
+	var decoded interface{}
+	if err := dec.Decode(&decoded); err != nil {
+		return SendResult{}, retryable(fmt.Errorf("decode webhook response: %w", err))
+	}
+
+	if p.mapping.SuccessPath != "" {
+		value, ok := lookupJSONPath(decoded, p.mapping.SuccessPath)
+		if !ok || value != p.mapping.SuccessValue {
+			return SendResult{}, permanent(fmt.Errorf("webhook rejected message %s", msg.ID))
+		}
+	}
+
+	var result SendResult
+	if p.mapping.RemoteIDPath != "" {
+		remoteID, ok := lookupJSONPath(decoded, p.mapping.RemoteIDPath)
+		if !ok || remoteID == "" {
+			return SendResult{}, permanent(fmt.Errorf("webhook response for %s has no %s", msg.ID, p.mapping.RemoteIDPath))
+		}
+		result.RemoteID = remoteID
+	}
+	return result, nil
+}
+
+// lookupJSONPath resolves a dot-separated path (object keys and array
+// indexes) in a decoded JSON document and returns the value as a string.
+func lookupJSONPath(doc interface{}, path string) (string, bool) {
+	current := doc
+	for _, part := range strings.Split(path, ".") {
+		switch node := current.(type) {
+		case map[string]interface{}:
+			next, ok := node[part]
+			if !ok {
+				return "", false
+			}
+			current = next
+		case []interface{}:
+			index, err := strconv.Atoi(part)
+			if err != nil || index < 0 || index >= len(node) {
+				return "", false
+			}
+			current = node[index]
+		default:
+			return "", false
+		}
+	}
+
+	switch value := current.(type) {
+	case string:
+		return value, true
+	case json.Number:
+		return value.String(), true
+	case bool:
+		return strconv.FormatBool(value), true
+	default:
+		return "", false
+	}
+}
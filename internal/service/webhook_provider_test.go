@@ -0,0 +1,89 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"automessaging/internal/model"
+)
+
+func TestWebhookProviderMapping(t *testing.T) {
+	var got map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if err := json.Unmarshal(body, &got); err != nil {
+			t.Errorf("request body is not JSON: %s", body)
+		}
+		if _, err := io.WriteString(w, `{"status":"queued","data":{"messages":[{"id":"abc-1"}]}}`); err != nil {
+			t.Errorf("write response: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	provider, err := NewWebhookProvider(WebhookProviderOptions{
+		Name: "gateway",
+		URL:  server.URL,
+		Mapping: WebhookMapping{
+			BodyTemplate: `{"destination":{{json .To}},"text":{{json .Content}}}`,
+			RemoteIDPath: "data.messages.0.id",
+			SuccessPath:  "status",
+			SuccessValue: "queued",
+		},
+	})
+	if err != nil {
+		t.Fatalf("new provider: %v", err)
+	}
+
+	result, err := provider.Send(context.Background(), model.Message{To: "+905551111111", Content: `say "hi"`})
+	if err != nil {
+		t.Fatalf("send: %v", err)
+	}
+	if result.RemoteID != "abc-1" {
+		t.Fatalf("expected remote id abc-1, got %q", result.RemoteID)
+	}
+	if got["destination"] != "+905551111111" || got["text"] != `say "hi"` {
+		t.Fatalf("unexpected request body: %v", got)
+	}
+}
+
+func TestWebhookProviderKeepsLargeNumericRemoteID(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, `{"message":"Accepted","messageId":1234567890123456789}`)
+	}))
+	defer server.Close()
+
+	provider, err := NewWebhookProvider(WebhookProviderOptions{Name: "gateway", URL: server.URL, Mapping: DefaultWebhookMapping()})
+	if err != nil {
+		t.Fatalf("new provider: %v", err)
+	}
+
+	result, err := provider.Send(context.Background(), model.Message{To: "+1", Content: "x"})
+	if err != nil {
+		t.Fatalf("send: %v", err)
+	}
+	if result.RemoteID != "1234567890123456789" {
+		t.Fatalf("expected remote id 1234567890123456789, got %q", result.RemoteID)
+	}
+}
+
+func TestWebhookProviderRejection(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, `{"message":"Rejected"}`)
+	}))
+	defer server.Close()
+
+	mapping := DefaultWebhookMapping()
+	provider, err := NewWebhookProvider(WebhookProviderOptions{Name: "gateway", URL: server.URL, Mapping: mapping})
+	if err != nil {
+		t.Fatalf("new provider: %v", err)
+	}
+
+	_, err = provider.Send(context.Background(), model.Message{To: "+1", Content: "x"})
+	if err == nil || asDeliveryError(err).Class != ErrorClassPermanent {
+		t.Fatalf("expected permanent error, got %v", err)
+	}
+}
@@ -0,0 +1,29 @@
+// Package timezone loads the IANA time zones recipients and schedules are
+// evaluated in. Postgres applies the same zones with AT TIME ZONE, so only
+// names both sides understand are accepted.
+package timezone
+
+import (
+	"errors"
+	"time"
+)
+
+// MaxLength is the longest zone name the database columns hold.
+const MaxLength = 64
+
+// ErrInvalid reports a name that is not a usable IANA time zone.
+var ErrInvalid = errors.New("must be an IANA time zone")
+
+// Load returns the named IANA time zone. Unlike time.LoadLocation it rejects
+// the empty name and "Local", which Go resolves to UTC and the server's own
+// zone but Postgres does not.
+func Load(name string) (*time.Location, error) {
+	if name == "" || name == "Local" || len(name) > MaxLength {
+		return nil, ErrInvalid
+	}
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, ErrInvalid
+	}
+	return location, nil
+}
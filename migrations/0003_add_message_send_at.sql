@@ -0,0 +1,3 @@
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS send_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS idx_messages_unsent_send_at ON messages (send_at, created_at) WHERE sent = false;
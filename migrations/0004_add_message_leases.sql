@@ -0,0 +1,9 @@
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS status VARCHAR(16) NOT NULL DEFAULT 'queued';
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS lease_owner VARCHAR(128);
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS lease_expires_at TIMESTAMPTZ;
+
+UPDATE messages SET status = 'sent' WHERE sent = true;
+
+DROP INDEX IF EXISTS idx_messages_unsent_send_at;
+CREATE INDEX IF NOT EXISTS idx_messages_queued_due ON messages (send_at, created_at) WHERE status = 'queued';
+CREATE INDEX IF NOT EXISTS idx_messages_processing_lease ON messages (lease_expires_at) WHERE status = 'processing';
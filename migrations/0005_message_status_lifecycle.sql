@@ -0,0 +1,13 @@
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS attempts INT NOT NULL DEFAULT 0;
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS last_error TEXT;
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS last_attempt_at TIMESTAMPTZ;
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS remote_message_id VARCHAR(128);
+
+UPDATE messages SET status = 'sent', attempts = 1, last_attempt_at = sent_at WHERE sent = true AND attempts = 0;
+
+ALTER TABLE messages DROP COLUMN IF EXISTS sent;
+
+ALTER TABLE messages ADD CONSTRAINT messages_status_check
+    CHECK (status IN ('queued', 'processing', 'sent', 'failed', 'cancelled', 'expired'));
+
+CREATE INDEX IF NOT EXISTS idx_messages_status_last_attempt_at ON messages (status, last_attempt_at DESC);
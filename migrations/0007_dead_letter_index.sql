@@ -0,0 +1 @@
+CREATE INDEX IF NOT EXISTS idx_messages_failed_last_attempt ON messages (last_attempt_at DESC) WHERE status = 'failed';
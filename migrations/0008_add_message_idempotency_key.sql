@@ -0,0 +1,6 @@
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS idempotency_key VARCHAR(255);
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS idempotency_fingerprint CHAR(64);
+
+-- Keys are global for now; once messages carry a tenant this index should
+-- become (tenant_id, idempotency_key).
+CREATE UNIQUE INDEX IF NOT EXISTS idx_messages_idempotency_key ON messages (idempotency_key) WHERE idempotency_key IS NOT NULL;
@@ -0,0 +1 @@
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS last_error_class VARCHAR(16);
@@ -0,0 +1,2 @@
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS route VARCHAR(32);
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS provider VARCHAR(64);
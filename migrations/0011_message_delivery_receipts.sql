@@ -0,0 +1,8 @@
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS receipt_at TIMESTAMPTZ;
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS carrier_error_code VARCHAR(32);
+
+ALTER TABLE messages DROP CONSTRAINT IF EXISTS messages_status_check;
+ALTER TABLE messages ADD CONSTRAINT messages_status_check
+    CHECK (status IN ('queued', 'processing', 'sent', 'delivered', 'undelivered', 'failed', 'cancelled', 'expired'));
+
+CREATE INDEX IF NOT EXISTS idx_messages_remote_message_id ON messages (remote_message_id) WHERE remote_message_id IS NOT NULL;
@@ -0,0 +1 @@
+CREATE INDEX IF NOT EXISTS idx_messages_accepted_sent_at ON messages (sent_at, id) WHERE remote_message_id IS NOT NULL;
@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS delivery_attempts (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    message_id UUID NOT NULL REFERENCES messages (id) ON DELETE CASCADE,
+    lease_owner VARCHAR(128) NOT NULL,
+    provider VARCHAR(64) NOT NULL,
+    state VARCHAR(16) NOT NULL DEFAULT 'dispatching'
+        CHECK (state IN ('dispatching', 'accepted', 'failed', 'unknown')),
+    remote_message_id VARCHAR(128),
+    error TEXT,
+    started_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    finished_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_delivery_attempts_message_id ON delivery_attempts (message_id, started_at DESC);
@@ -0,0 +1,5 @@
+CREATE INDEX IF NOT EXISTS idx_messages_sent_keyset ON messages (sent_at DESC, id DESC) WHERE status IN ('sent', 'delivered', 'undelivered');
+CREATE INDEX IF NOT EXISTS idx_messages_pending_keyset ON messages (created_at, id) WHERE status IN ('queued', 'processing');
+
+DROP INDEX IF EXISTS idx_messages_failed_last_attempt;
+CREATE INDEX IF NOT EXISTS idx_messages_failed_keyset ON messages (last_attempt_at DESC, id DESC) WHERE status = 'failed';
@@ -0,0 +1,7 @@
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS priority SMALLINT NOT NULL DEFAULT 5;
+
+ALTER TABLE messages DROP CONSTRAINT IF EXISTS messages_priority_check;
+ALTER TABLE messages ADD CONSTRAINT messages_priority_check CHECK (priority BETWEEN 0 AND 9);
+
+CREATE INDEX IF NOT EXISTS idx_messages_queued_priority ON messages (priority DESC, (COALESCE(next_attempt_at, send_at, created_at))) WHERE status = 'queued';
+CREATE INDEX IF NOT EXISTS idx_messages_queued_due_at ON messages ((COALESCE(next_attempt_at, send_at, created_at))) WHERE status = 'queued';
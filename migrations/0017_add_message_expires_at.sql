@@ -0,0 +1,3 @@
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS expires_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS idx_messages_queued_expires_at ON messages (expires_at) WHERE status = 'queued' AND expires_at IS NOT NULL;
@@ -0,0 +1,21 @@
+CREATE TABLE IF NOT EXISTS recurring_schedules (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    "to" VARCHAR(32) NOT NULL,
+    content VARCHAR(160) NOT NULL,
+    cron_expression VARCHAR(128) NOT NULL,
+    timezone VARCHAR(64) NOT NULL DEFAULT 'UTC',
+    priority SMALLINT NOT NULL DEFAULT 5 CHECK (priority BETWEEN 0 AND 9),
+    ttl_seconds INT CHECK (ttl_seconds > 0),
+    starts_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    ends_at TIMESTAMPTZ,
+    next_occurrence_at TIMESTAMPTZ,
+    active BOOLEAN NOT NULL DEFAULT true,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_recurring_schedules_due ON recurring_schedules (next_occurrence_at) WHERE active AND next_occurrence_at IS NOT NULL;
+
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS schedule_id UUID REFERENCES recurring_schedules (id) ON DELETE SET NULL;
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS occurrence_at TIMESTAMPTZ;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_messages_schedule_occurrence ON messages (schedule_id, occurrence_at) WHERE schedule_id IS NOT NULL;
@@ -0,0 +1,2 @@
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS recipient_timezone VARCHAR(64);
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS transactional BOOLEAN NOT NULL DEFAULT false;
@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS message_batches (
+    idempotency_key VARCHAR(255) PRIMARY KEY,
+    idempotency_fingerprint CHAR(64) NOT NULL,
+    result JSONB NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
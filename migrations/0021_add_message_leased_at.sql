@@ -0,0 +1 @@
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS leased_at TIMESTAMPTZ;
@@ -0,0 +1,4 @@
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS recipient_timezone_inferred BOOLEAN NOT NULL DEFAULT false;
+ALTER TABLE recurring_schedules ADD COLUMN IF NOT EXISTS recipient_timezone VARCHAR(64);
+ALTER TABLE recurring_schedules ADD COLUMN IF NOT EXISTS recipient_timezone_inferred BOOLEAN NOT NULL DEFAULT false;
+UPDATE recurring_schedules SET recipient_timezone = timezone WHERE recipient_timezone IS NULL AND timezone <> 'UTC';
@@ -0,0 +1,189 @@
+// Package webhooksig signs outbound webhook requests with HMAC-SHA256 and
+// lets receivers verify them.
+//
+// Each request carries three headers:
+//
+//	X-Signature-Timestamp: unix time in seconds
+//	X-Signature-Nonce:     random hex string, unique per request
+//	X-Signature:           v1=<hex hmac>[,v1=<hex hmac>...]
+//
+// The HMAC is computed over "<timestamp>.<nonce>.<body>". During key rotation
+// the sender signs with every active secret, so a receiver that knows any one
+// of them can verify the request.
+package webhooksig
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// HeaderSignature carries one or more versioned signatures.
+	HeaderSignature = "X-Signature"
+	// HeaderTimestamp carries the signing time as unix seconds.
+	HeaderTimestamp = "X-Signature-Timestamp"
+	// HeaderNonce carries a per-request random value.
+	HeaderNonce = "X-Signature-Nonce"
+
+	signatureVersion = "v1"
+)
+
+var (
+	// ErrNoSecrets is returned when a Signer or Verifier has no secrets.
+	ErrNoSecrets = errors.New("webhooksig: no secrets configured")
+	// ErrMissingHeaders is returned when a request lacks signature headers.
+	ErrMissingHeaders = errors.New("webhooksig: missing signature headers")
+	// ErrExpired is returned when the timestamp is outside the tolerance window.
+	ErrExpired = errors.New("webhooksig: timestamp outside tolerance")
+	// ErrInvalidSignature is returned when no signature matches any secret.
+	ErrInvalidSignature = errors.New("webhooksig: invalid signature")
+	// ErrReplayed is returned when a nonce was already seen.
+	ErrReplayed = errors.New("webhooksig: nonce already used")
+)
+
+// Signer adds signature headers to outbound requests.
+type Signer struct {
+	secrets [][]byte
+	now     func() time.Time
+}
+
+// NewSigner builds a Signer that signs with every given secret.
+func NewSigner(secrets ...string) (*Signer, error) {
+	keys := toKeys(secrets)
+	if len(keys) == 0 {
+		return nil, ErrNoSecrets
+	}
+	return &Signer{secrets: keys, now: time.Now}, nil
+}
+
+// Sign sets the timestamp, nonce and signature headers for body.
+func (s *Signer) Sign(header http.Header, body []byte) error {
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+
+	timestamp := strconv.FormatInt(s.now().Unix(), 10)
+	nonceHex := hex.EncodeToString(nonce)
+
+	signatures := make([]string, len(s.secrets))
+	for i, secret := range s.secrets {
+		signatures[i] = signatureVersion + "=" + hex.EncodeToString(compute(secret, timestamp, nonceHex, body))
+	}
+
+	header.Set(HeaderTimestamp, timestamp)
+	header.Set(HeaderNonce, nonceHex)
+	header.Set(HeaderSignature, strings.Join(signatures, ","))
+	return nil
+}
+
+// Verifier checks signatures on inbound requests.
+type Verifier struct {
+	secrets   [][]byte
+	tolerance time.Duration
+	now       func() time.Time
+
+	// SeenNonce, when set, is called with each verified nonce and should
+	// report whether it was already used within the tolerance window.
+	SeenNonce func(nonce string) bool
+}
+
+// NewVerifier builds a Verifier accepting any of secrets and timestamps
+// within tolerance of the current time (five minutes when zero).
+func NewVerifier(secrets []string, tolerance time.Duration) (*Verifier, error) {
+	keys := toKeys(secrets)
+	if len(keys) == 0 {
+		return nil, ErrNoSecrets
+	}
+	if tolerance <= 0 {
+		tolerance = 5 * time.Minute
+	}
+	return &Verifier{secrets: keys, tolerance: tolerance, now: time.Now}, nil
+}
+
+// Verify checks the signature headers against body.
+func (v *Verifier) Verify(header http.Header, body []byte) error {
+	timestamp := header.Get(HeaderTimestamp)
+	nonce := header.Get(HeaderNonce)
+	signatures := header.Get(HeaderSignature)
+	if timestamp == "" || nonce == "" || signatures == "" {
+		return ErrMissingHeaders
+	}
+
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+	age := v.now().Sub(time.Unix(unix, 0))
+	if age > v.tolerance || age < -v.tolerance {
+		return ErrExpired
+	}
+
+	if !v.matches(timestamp, nonce, body, signatures) {
+		return ErrInvalidSignature
+	}
+
+	if v.SeenNonce != nil && v.SeenNonce(nonce) {
+		return ErrReplayed
+	}
+	return nil
+}
+
+// VerifyRequest reads and verifies r's body, then restores it so handlers can read it again.
+func (v *Verifier) VerifyRequest(r *http.Request) error {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return err
+	}
+	r.Body.Close()
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	return v.Verify(r.Header, body)
+}
+
+func (v *Verifier) matches(timestamp, nonce string, body []byte, signatures string) bool {
+	for _, entry := range strings.Split(signatures, ",") {
+		version, value, ok := strings.Cut(strings.TrimSpace(entry), "=")
+		if !ok || version != signatureVersion {
+			continue
+		}
+		provided, err := hex.DecodeString(value)
+		if err != nil {
+			continue
+		}
+		for _, secret := range v.secrets {
+			if hmac.Equal(provided, compute(secret, timestamp, nonce, body)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func compute(secret []byte, timestamp, nonce string, body []byte) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write([]byte(nonce))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return mac.Sum(nil)
+}
+
+func toKeys(secrets []string) [][]byte {
+	keys := make([][]byte, 0, len(secrets))
+	for _, secret := range secrets {
+		if secret = strings.TrimSpace(secret); secret != "" {
+			keys = append(keys, []byte(secret))
+		}
+	}
+	return keys
+}
@@ -0,0 +1,70 @@
+package webhooksig
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestSignAndVerify(t *testing.T) {
+	signer, err := NewSigner("new-secret", "old-secret")
+	if err != nil {
+		t.Fatalf("new signer: %v", err)
+	}
+
+	body := []byte(`{"to":"+905551112233","content":"hi"}`)
+	header := http.Header{}
+	if err := signer.Sign(header, body); err != nil {
+		t.Fatalf("sign: %v", err)
+	}
+
+	// A receiver that has not rotated yet still accepts the request.
+	verifier, err := NewVerifier([]string{"old-secret"}, time.Minute)
+	if err != nil {
+		t.Fatalf("new verifier: %v", err)
+	}
+	if err := verifier.Verify(header, body); err != nil {
+		t.Fatalf("expected valid signature, got %v", err)
+	}
+
+	if err := verifier.Verify(header, []byte(`{"to":"+905551112233","content":"tampered"}`)); !errors.Is(err, ErrInvalidSignature) {
+		t.Fatalf("expected ErrInvalidSignature for tampered body, got %v", err)
+	}
+
+	unknown, _ := NewVerifier([]string{"other-secret"}, time.Minute)
+	if err := unknown.Verify(header, body); !errors.Is(err, ErrInvalidSignature) {
+		t.Fatalf("expected ErrInvalidSignature for unknown secret, got %v", err)
+	}
+}
+
+func TestVerifyRejectsStaleAndReplayed(t *testing.T) {
+	signer, _ := NewSigner("secret")
+	signer.now = func() time.Time { return time.Date(2030, 1, 1, 9, 0, 0, 0, time.UTC) }
+
+	body := []byte("payload")
+	header := http.Header{}
+	if err := signer.Sign(header, body); err != nil {
+		t.Fatalf("sign: %v", err)
+	}
+
+	verifier, _ := NewVerifier([]string{"secret"}, time.Minute)
+	verifier.now = func() time.Time { return time.Date(2030, 1, 1, 9, 5, 0, 0, time.UTC) }
+	if err := verifier.Verify(header, body); !errors.Is(err, ErrExpired) {
+		t.Fatalf("expected ErrExpired, got %v", err)
+	}
+
+	seen := map[string]bool{}
+	verifier.now = signer.now
+	verifier.SeenNonce = func(nonce string) bool {
+		used := seen[nonce]
+		seen[nonce] = true
+		return used
+	}
+	if err := verifier.Verify(header, body); err != nil {
+		t.Fatalf("expected first delivery to verify, got %v", err)
+	}
+	if err := verifier.Verify(header, body); !errors.Is(err, ErrReplayed) {
+		t.Fatalf("expected ErrReplayed, got %v", err)
+	}
+}